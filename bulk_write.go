@@ -0,0 +1,375 @@
+package fscli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// DefaultBulkWriteLimit is the number of documents a bulk UPDATE or DELETE
+// writes at most. A statement matching more fails before writing anything.
+const DefaultBulkWriteLimit = 1000
+
+// BulkWriteResult summarizes a bulk write.
+type BulkWriteResult struct {
+	Matched   int                `json:"matched"`
+	Succeeded int                `json:"succeeded"`
+	Failures  []BulkWriteFailure `json:"failures"`
+}
+
+// BulkWriteFailure is a document that could not be written.
+type BulkWriteFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// BulkWriteProgress is called each time a document has been written or
+// has failed.
+type BulkWriteProgress func(done int, total int)
+
+// ExecuteBulkUpdate applies the updates of op to every document its query
+// matches.
+func (exe *Executor) ExecuteBulkUpdate(ctx context.Context, op *UpdateOperation, progress BulkWriteProgress) (*BulkWriteResult, error) {
+	updates, err := exe.toUpdates(op.updates)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := exe.bulkTargets(ctx, "UPDATE", op.query)
+	if err != nil {
+		return nil, err
+	}
+	return exe.bulkWrite(ctx, refs, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+		return bw.Update(ref, updates)
+	}, progress)
+}
+
+// ExecuteBulkDelete deletes every document the query of op matches.
+// Subcollections of the deleted documents are left as they are.
+func (exe *Executor) ExecuteBulkDelete(ctx context.Context, op *DeleteOperation, progress BulkWriteProgress) (*BulkWriteResult, error) {
+	refs, err := exe.bulkTargets(ctx, "DELETE", op.query)
+	if err != nil {
+		return nil, err
+	}
+	return exe.bulkWrite(ctx, refs, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+		return bw.Delete(ref)
+	}, progress)
+}
+
+// CountBulkTargets counts the documents a bulk write of op would write.
+func (exe *Executor) CountBulkTargets(ctx context.Context, op *QueryOperation) (int64, error) {
+	count, err := exe.ExecuteCount(ctx, NewCountOperation(op.Collection(), op.filters))
+	if err != nil {
+		return 0, err
+	}
+	if op.limit > 0 && count > int64(op.limit) {
+		count = int64(op.limit)
+	}
+	return count, nil
+}
+
+// bulkTargets returns the documents matched by op, reading only their
+// names. It fails when more than bulkWriteLimit documents match.
+func (exe *Executor) bulkTargets(ctx context.Context, statement string, op *QueryOperation) ([]*firestore.DocumentRef, error) {
+	q, err := exe.buildQuery(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+	limit := exe.bulkWriteLimit + 1
+	if op.limit > 0 && op.limit < limit {
+		limit = op.limit
+	}
+
+	itr := q.Select().Limit(limit).Documents(ctx)
+	defer itr.Stop()
+
+	refs := make([]*firestore.DocumentRef, 0)
+	for {
+		doc, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, doc.Ref)
+	}
+	if len(refs) > exe.bulkWriteLimit {
+		return nil, fmt.Errorf("%w: %s writes at most %d documents, narrow it with WHERE or LIMIT", ErrTooManyDocuments, statement, exe.bulkWriteLimit)
+	}
+	return refs, nil
+}
+
+func (exe *Executor) bulkWrite(ctx context.Context, refs []*firestore.DocumentRef, write func(*firestore.BulkWriter, *firestore.DocumentRef) (*firestore.BulkWriterJob, error), progress BulkWriteProgress) (*BulkWriteResult, error) {
+	result := &BulkWriteResult{Matched: len(refs), Failures: []BulkWriteFailure{}}
+	if len(refs) == 0 {
+		return result, nil
+	}
+
+	bw := exe.fs.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, len(refs))
+	errs := make([]error, len(refs))
+	for i, ref := range refs {
+		jobs[i], errs[i] = write(bw, ref)
+	}
+
+	// End sends the last partial batch, so wait for the results meanwhile.
+	ended := make(chan struct{})
+	go func() {
+		bw.End()
+		close(ended)
+	}()
+
+	for i, job := range jobs {
+		err := errs[i]
+		if err == nil {
+			_, err = job.Results()
+		}
+		if err != nil {
+			result.Failures = append(result.Failures, BulkWriteFailure{Path: refPath(refs[i]), Error: err.Error()})
+		} else {
+			result.Succeeded++
+		}
+		if progress != nil {
+			progress(i+1, len(refs))
+		}
+	}
+	<-ended
+
+	return result, nil
+}
+
+// DeletedCollection is the number of documents deleted from a collection.
+type DeletedCollection struct {
+	Path    string `json:"path"`
+	Deleted int    `json:"deleted"`
+}
+
+// RecursiveDeleteResult summarizes a recursive delete. Collections are
+// listed in the order their first document was deleted, deepest first.
+type RecursiveDeleteResult struct {
+	Collections []DeletedCollection `json:"collections"`
+	Failures    []BulkWriteFailure  `json:"failures"`
+}
+
+// ExecuteRecursiveDelete deletes the document of op and every document
+// below it. When it fails midway, the documents deleted so far are
+// returned along with the error.
+func (exe *Executor) ExecuteRecursiveDelete(ctx context.Context, op *DeleteOperation, progress BulkWriteProgress) (*RecursiveDeleteResult, error) {
+	d := exe.newRecursiveDeleter(ctx, progress, false)
+	err := d.deleteDocument(exe.fs.Collection(op.Collection()).Doc(op.DocId()))
+	return d.end(), err
+}
+
+// ExecuteDropCollection deletes every document of a collection and every
+// document below them. Like ExecuteRecursiveDelete, it returns what was
+// deleted along with the error when it fails midway.
+func (exe *Executor) ExecuteDropCollection(ctx context.Context, op *DropCollectionOperation, progress BulkWriteProgress) (*RecursiveDeleteResult, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	d := exe.newRecursiveDeleter(ctx, progress, false)
+	err := d.deleteCollection(collection)
+	return d.end(), err
+}
+
+// errDescendantNotDeleted is the failure of a document that was kept
+// because a document below it could not be deleted.
+var errDescendantNotDeleted = errors.New("not deleted because a document below it could not be deleted")
+
+// writeBatchSize is how many writes are queued on a BulkWriter before
+// waiting for them.
+const writeBatchSize = 500
+
+// pendingWrite is a write sent to a BulkWriter whose result has not been
+// checked yet.
+type pendingWrite struct {
+	ref *firestore.DocumentRef
+	job *firestore.BulkWriterJob
+	err error
+}
+
+// collectionCount is the number of documents written to a collection.
+type collectionCount struct {
+	path string
+	n    int
+}
+
+// writeTracker queues writes on a BulkWriter and counts the written
+// documents per collection, in the order each collection was first
+// written. With dryRun it only counts the writes.
+type writeTracker struct {
+	bw          *firestore.BulkWriter
+	pending     []pendingWrite
+	done        int
+	collections map[string]int
+	counts      []collectionCount
+	failures    []BulkWriteFailure
+	progress    BulkWriteProgress
+	dryRun      bool
+}
+
+func (exe *Executor) newWriteTracker(ctx context.Context, progress BulkWriteProgress, dryRun bool) *writeTracker {
+	t := &writeTracker{
+		collections: map[string]int{},
+		counts:      []collectionCount{},
+		failures:    []BulkWriteFailure{},
+		progress:    progress,
+		dryRun:      dryRun,
+	}
+	if !dryRun {
+		t.bw = exe.fs.BulkWriter(ctx)
+	}
+	return t
+}
+
+// write queues the write of ref, waiting for the queued writes once
+// writeBatchSize of them are pending.
+func (t *writeTracker) write(ref *firestore.DocumentRef, write func(*firestore.BulkWriter, *firestore.DocumentRef) (*firestore.BulkWriterJob, error)) {
+	if t.dryRun {
+		t.count(ref)
+		return
+	}
+	job, err := write(t.bw, ref)
+	t.pending = append(t.pending, pendingWrite{ref: ref, job: job, err: err})
+	if len(t.pending) >= writeBatchSize {
+		t.flush()
+	}
+}
+
+// flush waits for the pending writes and counts them.
+func (t *writeTracker) flush() {
+	if t.dryRun || len(t.pending) == 0 {
+		return
+	}
+	t.bw.Flush()
+	for _, p := range t.pending {
+		err := p.err
+		if err == nil {
+			_, err = p.job.Results()
+		}
+		if err != nil {
+			t.failures = append(t.failures, BulkWriteFailure{Path: refPath(p.ref), Error: err.Error()})
+		} else {
+			t.count(p.ref)
+		}
+		t.done++
+		if t.progress != nil {
+			t.progress(t.done, 0)
+		}
+	}
+	t.pending = t.pending[:0]
+}
+
+func (t *writeTracker) count(ref *firestore.DocumentRef) {
+	path := refPath(ref)
+	collection := path[:strings.LastIndex(path, "/")]
+	i, ok := t.collections[collection]
+	if !ok {
+		i = len(t.counts)
+		t.collections[collection] = i
+		t.counts = append(t.counts, collectionCount{path: collection})
+	}
+	t.counts[i].n++
+}
+
+// end sends the remaining writes and closes the BulkWriter.
+func (t *writeTracker) end() {
+	if t.dryRun {
+		return
+	}
+	t.flush()
+	t.bw.End()
+}
+
+type recursiveDeleter struct {
+	ctx    context.Context
+	fs     *firestore.Client
+	writes *writeTracker
+	delete func(*firestore.BulkWriter, *firestore.DocumentRef) (*firestore.BulkWriterJob, error)
+}
+
+// newRecursiveDeleter returns a deleter. With dryRun it only counts the
+// documents it would delete.
+func (exe *Executor) newRecursiveDeleter(ctx context.Context, progress BulkWriteProgress, dryRun bool) *recursiveDeleter {
+	return &recursiveDeleter{
+		ctx:    ctx,
+		fs:     exe.fs,
+		writes: exe.newWriteTracker(ctx, progress, dryRun),
+		delete: func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+			return bw.Delete(ref)
+		},
+	}
+}
+
+// deleteDocument deletes the subcollections of ref, then ref itself.
+// A document is kept when a document below it could not be deleted, so
+// that nothing is left without its parent.
+func (d *recursiveDeleter) deleteDocument(ref *firestore.DocumentRef) error {
+	failures := len(d.writes.failures)
+	itr, err := getCollectionsIterator(d.ctx, d.fs, refPath(ref))
+	if err != nil {
+		return err
+	}
+	hasSubcollections := false
+	for {
+		col, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		hasSubcollections = true
+		if err := d.deleteCollection(col); err != nil {
+			return err
+		}
+	}
+
+	// BulkWriter sends its batches concurrently, so the deletes below ref
+	// must finish before ref is queued for it to be deleted last.
+	if hasSubcollections {
+		d.writes.flush()
+		prefix := refPath(ref) + "/"
+		for _, f := range d.writes.failures[failures:] {
+			if strings.HasPrefix(f.Path, prefix) {
+				d.writes.failures = append(d.writes.failures, BulkWriteFailure{Path: refPath(ref), Error: errDescendantNotDeleted.Error()})
+				return nil
+			}
+		}
+	}
+	d.writes.write(ref, d.delete)
+	return nil
+}
+
+// deleteCollection deletes every document of col, including documents
+// that only exist as the parent of a subcollection.
+func (d *recursiveDeleter) deleteCollection(col *firestore.CollectionRef) error {
+	itr := col.DocumentRefs(d.ctx)
+	for {
+		ref, err := itr.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := d.deleteDocument(ref); err != nil {
+			return err
+		}
+	}
+}
+
+// end sends the remaining deletes and returns what was deleted.
+func (d *recursiveDeleter) end() *RecursiveDeleteResult {
+	d.writes.end()
+	result := &RecursiveDeleteResult{Collections: make([]DeletedCollection, 0, len(d.writes.counts)), Failures: d.writes.failures}
+	for _, c := range d.writes.counts {
+		result.Collections = append(result.Collections, DeletedCollection{Path: c.path, Deleted: c.n})
+	}
+	return result
+}
@@ -22,6 +22,14 @@ func main() {
 				Usage: "output mode (table or json)",
 				Value: "table",
 			},
+			&cli.BoolFlag{
+				Name:  "read-only",
+				Usage: "reject statements that change data",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "show what statements would change without writing",
+			},
 		},
 		Action: func(cCtx *cli.Context) error {
 			projectId := cCtx.String("project-id")
@@ -35,7 +43,14 @@ func main() {
 
 			outModeFlag := cCtx.String("out-mode")
 
+			config, err := fscli.LoadProjectConfig(projectId)
+			if err != nil {
+				return err
+			}
+
 			repl := fscli.NewRepl(cCtx.Context, fs, os.Stdin, os.Stdout, fscli.OutputMode(outModeFlag))
+			repl.SetReadOnly(cCtx.Bool("read-only") || config.ReadOnly)
+			repl.SetDryRun(cCtx.Bool("dry-run"))
 
 			// check stdin
 			fi, err := os.Stdin.Stat()
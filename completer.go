@@ -11,27 +11,132 @@ var (
 	getSuggestion   = prompt.Suggest{Text: "GET", Description: "GET [docPath]"}
 	querySuggestion = prompt.Suggest{Text: "QUERY", Description: "QUERY [collection]"}
 	countSuggestion = prompt.Suggest{Text: "COUNT", Description: "COUNT [collection]"}
+
+	aggregateSuggestion = prompt.Suggest{Text: "AGGREGATE", Description: "AGGREGATE [collection] [aggregation...]"}
+	explainSuggestion   = prompt.Suggest{Text: "EXPLAIN", Description: "EXPLAIN [ANALYZE] QUERY [collection]"}
+	analyzeSuggestion   = prompt.Suggest{Text: "ANALYZE", Description: "ANALYZE QUERY [collection]"}
+	insertSuggestion    = prompt.Suggest{Text: "INSERT", Description: "INSERT INTO [collection] [ID id] VALUES {object}"}
+	setSuggestion       = prompt.Suggest{Text: "SET", Description: "SET [docPath] {object} [MERGE]"}
+	updateSuggestion    = prompt.Suggest{Text: "UPDATE", Description: "UPDATE [path] SET [field] = [value]... [WHERE ...]"}
+	deleteSuggestion    = prompt.Suggest{Text: "DELETE", Description: "DELETE [docPath] [RECURSIVE] | DELETE FROM [collection] [WHERE ...]"}
+	dropSuggestion      = prompt.Suggest{Text: "DROP", Description: "DROP COLLECTION [collection]"}
+	copySuggestion      = prompt.Suggest{Text: "COPY", Description: "COPY [docPath] TO [docPath] [RECURSIVE] | COPY COLLECTION [collection] TO [collection] [WHERE ...]"}
+	beginSuggestion     = prompt.Suggest{Text: "BEGIN", Description: "BEGIN a transaction"}
+	commitSuggestion    = prompt.Suggest{Text: "COMMIT", Description: "COMMIT the transaction"}
+	rollbackSuggestion  = prompt.Suggest{Text: "ROLLBACK", Description: "ROLLBACK the transaction"}
 )
 
 var rootSuggestions = []prompt.Suggest{
 	getSuggestion,
 	querySuggestion,
 	countSuggestion,
+	aggregateSuggestion,
+	explainSuggestion,
+	insertSuggestion,
+	setSuggestion,
+	updateSuggestion,
+	deleteSuggestion,
+	dropSuggestion,
+	copySuggestion,
+	beginSuggestion,
+	commitSuggestion,
+	rollbackSuggestion,
+}
+
+var (
+	intoSuggestion       = prompt.Suggest{Text: "INTO", Description: "INTO [collection]"}
+	idSuggestion         = prompt.Suggest{Text: "ID", Description: "ID [id]"}
+	valuesSuggestion     = prompt.Suggest{Text: "VALUES", Description: "VALUES {object}"}
+	mergeSuggestion      = prompt.Suggest{Text: "MERGE", Description: "MERGE"}
+	updateSetSuggestion  = prompt.Suggest{Text: "SET", Description: "SET [field] = [value]..."}
+	fromSuggestion       = prompt.Suggest{Text: "FROM", Description: "FROM [collection]"}
+	writeLimitSuggestion = prompt.Suggest{Text: "LIMIT", Description: "LIMIT [count]"}
+	recursiveSuggestion  = prompt.Suggest{Text: "RECURSIVE", Description: "RECURSIVE"}
+	collectionSuggestion = prompt.Suggest{Text: "COLLECTION", Description: "COLLECTION [collection]"}
+	ifSuggestion         = prompt.Suggest{Text: "IF", Description: "IF [precondition]"}
+	copyToSuggestion     = prompt.Suggest{Text: "TO", Description: "TO [path]"}
+)
+
+var preconditionSuggestions = []prompt.Suggest{
+	{Text: "EXISTS", Description: "EXISTS"},
+	{Text: "NOT", Description: "NOT EXISTS"},
+	{Text: "UPDATED_AT", Description: "UPDATED_AT = TIMESTAMP(time)"},
+}
+
+var transformSuggestions = []prompt.Suggest{
+	{Text: "INCREMENT", Description: "INCREMENT(number)"},
+	{Text: "ARRAY_UNION", Description: "ARRAY_UNION(values...)"},
+	{Text: "ARRAY_REMOVE", Description: "ARRAY_REMOVE(values...)"},
+	{Text: "DELETE_FIELD", Description: "DELETE_FIELD()"},
+	{Text: "SERVER_TIMESTAMP", Description: "SERVER_TIMESTAMP()"},
+}
+
+var (
+	countAllSuggestion = prompt.Suggest{Text: "COUNT(*)", Description: "COUNT(*) [AS alias]"}
+	sumSuggestion      = prompt.Suggest{Text: "SUM", Description: "SUM(field) [AS alias]"}
+	avgSuggestion      = prompt.Suggest{Text: "AVG", Description: "AVG(field) [AS alias]"}
+	asSuggestion       = prompt.Suggest{Text: "AS", Description: "AS [alias]"}
+)
+
+var aggregationSuggestions = []prompt.Suggest{
+	countAllSuggestion,
+	sumSuggestion,
+	avgSuggestion,
 }
 
 var (
 	selectSuggestion          = prompt.Suggest{Text: "SELECT", Description: "SELECT [field...]"}
 	whereSuggestion           = prompt.Suggest{Text: "WHERE", Description: "WHERE [field] [operator] [value]"}
+	groupBySuggestion         = prompt.Suggest{Text: "GROUP BY", Description: "GROUP BY [field...]"}
 	orderBySuggestion         = prompt.Suggest{Text: "ORDER BY", Description: "ORDER BY [field] [ASC/DESC]"}
-	limitSuggestion           = prompt.Suggest{Text: "LIMIT", Description: "LIMIT [count]"}
+	limitSuggestion           = prompt.Suggest{Text: "LIMIT", Description: "LIMIT [LAST] [count]"}
+	lastSuggestion            = prompt.Suggest{Text: "LAST", Description: "LAST [count]"}
+	offsetSuggestion          = prompt.Suggest{Text: "OFFSET", Description: "OFFSET [count]"}
+	startAtSuggestion         = prompt.Suggest{Text: "START AT", Description: "START AT [values...|docPath]"}
+	startAfterSuggestion      = prompt.Suggest{Text: "START AFTER", Description: "START AFTER [values...|docPath]"}
+	endAtSuggestion           = prompt.Suggest{Text: "END AT", Description: "END AT [values...|docPath]"}
+	endBeforeSuggestion       = prompt.Suggest{Text: "END BEFORE", Description: "END BEFORE [values...|docPath]"}
+	asOfSuggestion            = prompt.Suggest{Text: "AS OF", Description: "AS OF TIMESTAMP([time])"}
+	findNearestSuggestion     = prompt.Suggest{Text: "FIND NEAREST", Description: "FIND NEAREST [field] TO VECTOR([values]) DISTANCE [measure] LIMIT [count]"}
+	distanceFieldSuggestion   = prompt.Suggest{Text: "DISTANCE FIELD", Description: "DISTANCE FIELD [field]"}
 	collectionGroupSuggestion = prompt.Suggest{Text: "COLLECTION_GROUP", Description: "COLLECTION_GROUP [collection]"}
 )
 
 var querySuggestions = []prompt.Suggest{
 	selectSuggestion,
 	whereSuggestion,
+	findNearestSuggestion,
+	groupBySuggestion,
 	orderBySuggestion,
+	startAtSuggestion,
+	startAfterSuggestion,
+	endAtSuggestion,
+	endBeforeSuggestion,
 	limitSuggestion,
+	offsetSuggestion,
+	asOfSuggestion,
+}
+
+var distanceMeasureSuggestions = []prompt.Suggest{
+	{Text: "COSINE", Description: "COSINE"},
+	{Text: "EUCLIDEAN", Description: "EUCLIDEAN"},
+	{Text: "DOT_PRODUCT", Description: "DOT_PRODUCT"},
+}
+
+var (
+	inSuggestion               = prompt.Suggest{Text: "IN", Description: "IN [values]"}
+	notInSuggestion            = prompt.Suggest{Text: "NOT_IN", Description: "NOT_IN [values]"}
+	arrayContainsSuggestion    = prompt.Suggest{Text: "ARRAY_CONTAINS", Description: "ARRAY_CONTAINS [value]"}
+	arrayContainsAnySuggestion = prompt.Suggest{Text: "ARRAY_CONTAINS_ANY", Description: "ARRAY_CONTAINS_ANY [values]"}
+	isSuggestion               = prompt.Suggest{Text: "IS", Description: "IS [NOT] NULL/NAN"}
+)
+
+var operatorSuggestions = []prompt.Suggest{
+	inSuggestion,
+	notInSuggestion,
+	arrayContainsSuggestion,
+	arrayContainsAnySuggestion,
+	isSuggestion,
 }
 
 var (
@@ -76,6 +181,30 @@ func (c *Completer) Parse() ([]prompt.Suggest, error) {
 	if c.curTokenIs(COUNT) {
 		return c.parseCountOperation()
 	}
+	if c.curTokenIs(AGGREGATE) {
+		return c.parseAggregateOperation()
+	}
+	if c.curTokenIs(EXPLAIN) {
+		return c.parseExplainOperation()
+	}
+	if c.curTokenIs(INSERT) {
+		return c.parseInsertOperation()
+	}
+	if c.curTokenIs(SET) {
+		return c.parseSetOperation()
+	}
+	if c.curTokenIs(UPDATE) {
+		return c.parseUpdateOperation()
+	}
+	if c.curTokenIs(DELETE) {
+		return c.parseDeleteOperation()
+	}
+	if c.curTokenIs(DROP) {
+		return c.parseDropOperation()
+	}
+	if c.curTokenIs(COPY) {
+		return c.parseCopyOperation()
+	}
 
 	if c.curTokenIs(IDENT) {
 		return prompt.FilterHasPrefix(rootSuggestions, c.curToken.Literal, true), nil
@@ -84,7 +213,7 @@ func (c *Completer) Parse() ([]prompt.Suggest, error) {
 }
 
 func (c *Completer) parseQueryOperation() ([]prompt.Suggest, error) {
-	if !c.peekTokenIs(IDENT) && !c.peekTokenIs(COLLECTION_GROUP) {
+	if !isName(c.peekToken) {
 		return []prompt.Suggest{}, nil
 	}
 	c.nextToken()
@@ -134,7 +263,7 @@ func (c *Completer) parseQueryOperation() ([]prompt.Suggest, error) {
 	}
 
 	// select / where / order by / limit
-	if c.curTokenIs(IDENT) {
+	if c.curTokenIs(IDENT) && !c.curTokenIsFindNearest() {
 		return prompt.FilterHasPrefix(querySuggestions, c.curToken.Literal, true), nil
 	}
 
@@ -145,50 +274,72 @@ func (c *Completer) parseQueryOperation() ([]prompt.Suggest, error) {
 			return []prompt.Suggest{}, nil
 		}
 
+		// skip fields and aggregations
 		for !c.curTokenIs(EOF) {
-			if c.curTokenIs(COMMA) {
+			c.nextToken()
+			if c.curTokenIs(LPAREN) {
+				for !c.curTokenIs(RPAREN) && !c.curTokenIs(EOF) {
+					c.nextToken()
+				}
 				c.nextToken()
-			} else {
+			}
+			if c.curTokenIs(AS) {
 				c.nextToken()
+				c.nextToken()
+			}
+			if !c.curTokenIs(COMMA) {
 				break
 			}
+			c.nextToken()
 		}
 	}
 
 	if c.curTokenIs(EOF) {
 		return []prompt.Suggest{}, nil
 	}
-	// where / order by / limit
-	if c.curTokenIs(IDENT) {
+	// where / group by / order by / limit
+	if c.curTokenIs(IDENT) && !c.curTokenIsFindNearest() {
 		return prompt.FilterHasPrefix(querySuggestions[1:], c.curToken.Literal, true), nil
 	}
 
 	if c.curTokenIs(WHERE) {
-		c.nextToken()
+		if suggestions, ok := c.parseWhere(); !ok {
+			return suggestions, nil
+		}
+	}
 
-		if c.curTokenIs(EOF) {
-			return []prompt.Suggest{}, nil
+	if c.curTokenIs(EOF) {
+		return []prompt.Suggest{}, nil
+	}
+	// find nearest / group by / order by / limit
+	if c.curTokenIs(IDENT) && !c.curTokenIsFindNearest() {
+		return prompt.FilterHasPrefix(querySuggestions[2:], c.curToken.Literal, true), nil
+	}
+
+	if c.curTokenIsFindNearest() {
+		if suggestions, ok := c.parseFindNearest(); !ok {
+			return suggestions, nil
+		}
+		// as of
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{asOfSuggestion}, c.curToken.Literal, true), nil
 		}
+		return []prompt.Suggest{}, nil
+	}
 
+	if c.curTokenIs(GROUP) {
 		c.nextToken()
-		for !c.curTokenIs(EOF) {
-			if c.curTokenIsOperator() {
-				c.nextToken()
-				// value
+		if c.curTokenIs(BY) {
+			c.nextToken()
+		}
 
-				if c.curTokenIs(EOF) {
-					return []prompt.Suggest{}, nil
-				}
-				c.nextToken()
-			} else if c.curTokenIs(AND) {
-				c.nextToken()
-				if c.curTokenIs(EOF) {
-					return []prompt.Suggest{}, nil
-				}
-				c.nextToken()
-			} else {
+		// skip group by fields
+		for !c.curTokenIs(EOF) {
+			c.nextToken()
+			if !c.curTokenIs(COMMA) {
 				break
 			}
+			c.nextToken()
 		}
 	}
 
@@ -197,7 +348,7 @@ func (c *Completer) parseQueryOperation() ([]prompt.Suggest, error) {
 	}
 	// order by / limit
 	if c.curTokenIs(IDENT) {
-		return prompt.FilterHasPrefix(querySuggestions[2:], c.curToken.Literal, true), nil
+		return prompt.FilterHasPrefix(querySuggestions[4:], c.curToken.Literal, true), nil
 	}
 
 	if c.curTokenIs(ORDER) {
@@ -235,14 +386,116 @@ func (c *Completer) parseQueryOperation() ([]prompt.Suggest, error) {
 	if c.curTokenIs(EOF) {
 		return []prompt.Suggest{}, nil
 	}
-	// asc/ desc / limit
+	// asc/ desc / start / end / limit / offset
+	if c.curTokenIs(IDENT) {
+		return prompt.FilterHasPrefix(append([]prompt.Suggest{ascSuggestion, descSuggestion}, querySuggestions[5:]...), c.curToken.Literal, true), nil
+	}
+
+	if c.curTokenIs(START) {
+		if !c.skipCursor() {
+			return []prompt.Suggest{}, nil
+		}
+	}
+
+	if c.curTokenIs(EOF) {
+		return []prompt.Suggest{}, nil
+	}
+	// end / limit / offset
+	if c.curTokenIs(IDENT) {
+		return prompt.FilterHasPrefix(querySuggestions[7:], c.curToken.Literal, true), nil
+	}
+
+	if c.curTokenIs(END) {
+		if !c.skipCursor() {
+			return []prompt.Suggest{}, nil
+		}
+	}
+
+	if c.curTokenIs(EOF) {
+		return []prompt.Suggest{}, nil
+	}
+	// limit / offset
+	if c.curTokenIs(IDENT) {
+		return prompt.FilterHasPrefix(querySuggestions[9:], c.curToken.Literal, true), nil
+	}
+
+	if c.curTokenIs(LIMIT) {
+		c.nextToken()
+		// last
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{lastSuggestion}, c.curToken.Literal, true), nil
+		}
+		if c.curTokenIs(LAST) {
+			c.nextToken()
+		}
+		if c.curTokenIs(EOF) {
+			return []prompt.Suggest{}, nil
+		}
+		c.nextToken()
+	}
+
+	if c.curTokenIs(EOF) {
+		return []prompt.Suggest{}, nil
+	}
+	// offset / as of
 	if c.curTokenIs(IDENT) {
-		return prompt.FilterHasPrefix([]prompt.Suggest{ascSuggestion, descSuggestion, limitSuggestion}, c.curToken.Literal, true), nil
+		return prompt.FilterHasPrefix(querySuggestions[10:], c.curToken.Literal, true), nil
+	}
+
+	if c.curTokenIs(OFFSET) {
+		c.nextToken()
+		if c.curTokenIs(EOF) {
+			return []prompt.Suggest{}, nil
+		}
+		c.nextToken()
+	}
+
+	// as of
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{asOfSuggestion}, c.curToken.Literal, true), nil
 	}
 
 	return []prompt.Suggest{}, nil
 }
 
+// parseFindNearest advances past FIND NEAREST.
+// It returns false with suggestions when the input ends inside it.
+func (c *Completer) parseFindNearest() ([]prompt.Suggest, bool) {
+	for !c.curTokenIs(EOF) {
+		// measure
+		if c.curTokenIsWord(DISTANCE) && c.peekTokenIs(IDENT) {
+			c.nextToken()
+			if c.peekTokenIs(EOF) {
+				return prompt.FilterHasPrefix(distanceMeasureSuggestions, c.curToken.Literal, true), false
+			}
+		}
+
+		if c.curTokenIs(LIMIT) {
+			c.nextToken()
+			if c.curTokenIs(EOF) {
+				return []prompt.Suggest{}, false
+			}
+			c.nextToken()
+
+			// distance field / as of
+			if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+				return prompt.FilterHasPrefix([]prompt.Suggest{distanceFieldSuggestion, asOfSuggestion}, c.curToken.Literal, true), false
+			}
+			if c.curTokenIsWord(DISTANCE) {
+				for i := 0; i < 3; i++ {
+					if c.curTokenIs(EOF) {
+						return []prompt.Suggest{}, false
+					}
+					c.nextToken()
+				}
+			}
+			return nil, true
+		}
+		c.nextToken()
+	}
+	return []prompt.Suggest{}, false
+}
+
 func (c *Completer) parseGetOperation() ([]prompt.Suggest, error) {
 	if !c.expectPeek(IDENT) {
 		return []prompt.Suggest{}, nil
@@ -252,7 +505,7 @@ func (c *Completer) parseGetOperation() ([]prompt.Suggest, error) {
 		parts := strings.Split(docPath, "/")
 		if len(parts)%2 == 0 {
 			// complete doc path, suggest SELECT
-			return []prompt.Suggest{selectSuggestion}, nil
+			return []prompt.Suggest{selectSuggestion, asOfSuggestion}, nil
 		}
 		var baseDoc string
 		if len(parts) == 1 {
@@ -276,11 +529,11 @@ func (c *Completer) parseGetOperation() ([]prompt.Suggest, error) {
 	c.nextToken()
 
 	if c.curTokenIs(EOF) {
-		return []prompt.Suggest{selectSuggestion}, nil
+		return []prompt.Suggest{selectSuggestion, asOfSuggestion}, nil
 	}
 
 	if c.curTokenIs(IDENT) {
-		return prompt.FilterHasPrefix([]prompt.Suggest{selectSuggestion}, c.curToken.Literal, true), nil
+		return prompt.FilterHasPrefix([]prompt.Suggest{selectSuggestion, asOfSuggestion}, c.curToken.Literal, true), nil
 	}
 
 	if c.curTokenIs(SELECT) {
@@ -292,21 +545,24 @@ func (c *Completer) parseGetOperation() ([]prompt.Suggest, error) {
 
 		// skip select fields
 		for !c.curTokenIs(EOF) {
-			if c.curTokenIs(COMMA) {
-				c.nextToken()
-			} else {
-				c.nextToken()
+			c.nextToken()
+			if !c.curTokenIs(COMMA) {
+				break
 			}
+			c.nextToken()
 		}
 
-		return []prompt.Suggest{}, nil
+		// as of
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{asOfSuggestion}, c.curToken.Literal, true), nil
+		}
 	}
 
 	return []prompt.Suggest{}, nil
 }
 
 func (c *Completer) parseCountOperation() ([]prompt.Suggest, error) {
-	if !c.peekTokenIs(IDENT) && !c.peekTokenIs(COLLECTION_GROUP) {
+	if !isName(c.peekToken) {
 		return []prompt.Suggest{}, nil
 	}
 	c.nextToken()
@@ -354,9 +610,9 @@ func (c *Completer) parseCountOperation() ([]prompt.Suggest, error) {
 		return []prompt.Suggest{}, nil
 	}
 
-	// where
+	// where / as of
 	if c.curTokenIs(IDENT) {
-		return prompt.FilterHasPrefix([]prompt.Suggest{whereSuggestion}, c.curToken.Literal, true), nil
+		return prompt.FilterHasPrefix([]prompt.Suggest{whereSuggestion, asOfSuggestion}, c.curToken.Literal, true), nil
 	}
 
 	if c.curTokenIs(EOF) {
@@ -364,35 +620,480 @@ func (c *Completer) parseCountOperation() ([]prompt.Suggest, error) {
 	}
 
 	if c.curTokenIs(WHERE) {
+		if suggestions, ok := c.parseWhere(); !ok {
+			return suggestions, nil
+		}
+	}
+
+	// as of
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{asOfSuggestion}, c.curToken.Literal, true), nil
+	}
+
+	return []prompt.Suggest{}, nil
+}
+
+func (c *Completer) parseExplainOperation() ([]prompt.Suggest, error) {
+	if c.peekTokenIs(IDENT) {
+		c.nextToken()
+		if c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{analyzeSuggestion, querySuggestion}, c.curToken.Literal, true), nil
+		}
+		return []prompt.Suggest{}, nil
+	}
+
+	if c.peekTokenIs(ANALYZE) {
+		c.nextToken()
+		if c.peekTokenIs(IDENT) {
+			c.nextToken()
+			if c.peekTokenIs(EOF) {
+				return prompt.FilterHasPrefix([]prompt.Suggest{querySuggestion}, c.curToken.Literal, true), nil
+			}
+			return []prompt.Suggest{}, nil
+		}
+	}
+
+	if !c.expectPeek(QUERY) {
+		return []prompt.Suggest{}, nil
+	}
+	return c.parseQueryOperation()
+}
+
+func (c *Completer) parseInsertOperation() ([]prompt.Suggest, error) {
+	// IN is a keyword, so INTO is typed through it
+	if c.peekTokenIs(IDENT) || c.peekTokenIs(IN) {
 		c.nextToken()
+		if c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{intoSuggestion}, c.curToken.Literal, true), nil
+		}
+		return []prompt.Suggest{}, nil
+	}
+
+	if !c.expectPeek(INTO) {
+		return []prompt.Suggest{}, nil
+	}
+	if !c.expectPeek(IDENT) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.peekTokenIs(EOF) {
+		return c.collectionSuggestions(c.curToken.Literal), nil
+	}
+
+	c.nextToken()
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{idSuggestion, valuesSuggestion}, c.curToken.Literal, true), nil
+	}
+	if c.curTokenIsWord(ID) {
+		if !c.expectPeek(STRING) {
+			return []prompt.Suggest{}, nil
+		}
+		if c.expectPeek(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{valuesSuggestion}, c.curToken.Literal, true), nil
+		}
+	}
+
+	return []prompt.Suggest{}, nil
+}
+
+func (c *Completer) parseSetOperation() ([]prompt.Suggest, error) {
+	if !c.expectPeek(IDENT) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.peekTokenIs(EOF) {
+		return c.collectionSuggestions(c.curToken.Literal), nil
+	}
+
+	if !c.expectPeek(LBRACE) {
+		return []prompt.Suggest{}, nil
+	}
+	c.skipValue()
+	c.nextToken()
+
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{mergeSuggestion, ifSuggestion}, c.curToken.Literal, true), nil
+	}
+	if c.curTokenIs(MERGE) {
+		c.nextToken()
+	}
+
+	return c.parsePrecondition(), nil
+}
+
+func (c *Completer) parseUpdateOperation() ([]prompt.Suggest, error) {
+	if !c.expectPeek(IDENT) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.peekTokenIs(EOF) {
+		return c.collectionSuggestions(c.curToken.Literal), nil
+	}
+	bulk := len(strings.Split(normalizeFirestorePath(c.curToken.Literal), "/"))%2 != 0
+
+	c.nextToken()
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{updateSetSuggestion}, c.curToken.Literal, true), nil
+	}
+	if !c.curTokenIs(SET) {
+		return []prompt.Suggest{}, nil
+	}
 
+	// skip assignments, suggesting transforms for their values
+	for {
+		c.nextToken()
+		if !c.expectPeek(EQ) {
+			return []prompt.Suggest{}, nil
+		}
+		c.nextToken()
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix(transformSuggestions, c.curToken.Literal, true), nil
+		}
 		if c.curTokenIs(EOF) {
 			return []prompt.Suggest{}, nil
 		}
+		c.skipValue()
+		c.nextToken()
+		if !c.curTokenIs(COMMA) {
+			break
+		}
+	}
+
+	if !bulk {
+		return c.parsePrecondition(), nil
+	}
+	return c.parseWriteTargets(), nil
+}
 
+func (c *Completer) parseDeleteOperation() ([]prompt.Suggest, error) {
+	if c.peekTokenIs(IDENT) {
 		c.nextToken()
-		for !c.curTokenIs(EOF) {
-			if c.curTokenIsOperator() {
-				c.nextToken()
-				// value
+		if c.peekTokenIs(EOF) {
+			suggestions := prompt.FilterHasPrefix([]prompt.Suggest{fromSuggestion}, c.curToken.Literal, true)
+			return append(suggestions, c.collectionSuggestions(c.curToken.Literal)...), nil
+		}
+		c.nextToken()
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{recursiveSuggestion, ifSuggestion}, c.curToken.Literal, true), nil
+		}
+		return c.parsePrecondition(), nil
+	}
 
-				if c.curTokenIs(EOF) {
-					return []prompt.Suggest{}, nil
-				}
-				c.nextToken()
-			} else if c.curTokenIs(AND) {
-				c.nextToken()
-				if c.curTokenIs(EOF) {
-					return []prompt.Suggest{}, nil
-				}
+	if !c.expectPeek(FROM) {
+		return []prompt.Suggest{}, nil
+	}
+	if !c.expectPeek(IDENT) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.peekTokenIs(EOF) {
+		return c.collectionSuggestions(c.curToken.Literal), nil
+	}
+
+	c.nextToken()
+	return c.parseWriteTargets(), nil
+}
+
+func (c *Completer) parseDropOperation() ([]prompt.Suggest, error) {
+	if c.peekTokenIs(IDENT) {
+		c.nextToken()
+		if c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{collectionSuggestion}, c.curToken.Literal, true), nil
+		}
+		return []prompt.Suggest{}, nil
+	}
+
+	if !c.expectPeek(COLLECTION) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.expectPeek(IDENT) && c.peekTokenIs(EOF) {
+		return c.collectionSuggestions(c.curToken.Literal), nil
+	}
+	return []prompt.Suggest{}, nil
+}
+
+func (c *Completer) parseCopyOperation() ([]prompt.Suggest, error) {
+	collection := c.peekTokenIs(COLLECTION)
+	if collection {
+		c.nextToken()
+	}
+	if !c.expectPeek(IDENT) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.peekTokenIs(EOF) {
+		suggestions := []prompt.Suggest{}
+		if !collection {
+			suggestions = prompt.FilterHasPrefix([]prompt.Suggest{collectionSuggestion}, c.curToken.Literal, true)
+		}
+		return append(suggestions, c.collectionSuggestions(c.curToken.Literal)...), nil
+	}
+
+	c.nextToken()
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{copyToSuggestion}, c.curToken.Literal, true), nil
+	}
+	if !c.curTokenIsWord(TO) || !c.expectPeek(IDENT) {
+		return []prompt.Suggest{}, nil
+	}
+	if c.peekTokenIs(EOF) {
+		return c.collectionSuggestions(c.curToken.Literal), nil
+	}
+
+	c.nextToken()
+	if !collection {
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{recursiveSuggestion}, c.curToken.Literal, true), nil
+		}
+		return []prompt.Suggest{}, nil
+	}
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{whereSuggestion, writeLimitSuggestion, recursiveSuggestion}, c.curToken.Literal, true), nil
+	}
+	if c.curTokenIs(WHERE) {
+		if suggestions, ok := c.parseWhere(); !ok {
+			return suggestions, nil
+		}
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{writeLimitSuggestion, recursiveSuggestion}, c.curToken.Literal, true), nil
+		}
+	}
+	return []prompt.Suggest{}, nil
+}
+
+// parsePrecondition suggests the IF clause of a write of one document.
+func (c *Completer) parsePrecondition() []prompt.Suggest {
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{ifSuggestion}, c.curToken.Literal, true)
+	}
+	if c.curTokenIs(IF) && c.expectPeek(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix(preconditionSuggestions, c.curToken.Literal, true)
+	}
+	return []prompt.Suggest{}
+}
+
+// parseWriteTargets suggests the WHERE and LIMIT of a bulk write.
+func (c *Completer) parseWriteTargets() []prompt.Suggest {
+	if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+		return prompt.FilterHasPrefix([]prompt.Suggest{whereSuggestion, writeLimitSuggestion}, c.curToken.Literal, true)
+	}
+
+	if c.curTokenIs(WHERE) {
+		if suggestions, ok := c.parseWhere(); !ok {
+			return suggestions
+		}
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix([]prompt.Suggest{writeLimitSuggestion}, c.curToken.Literal, true)
+		}
+	}
+
+	return []prompt.Suggest{}
+}
+
+// collectionSuggestions suggests the collections under the document that
+// the partial path is in.
+func (c *Completer) collectionSuggestions(literal string) []prompt.Suggest {
+	parts := strings.Split(normalizeFirestorePath(literal), "/")
+	if len(parts)%2 == 0 {
+		return []prompt.Suggest{}
+	}
+	baseDoc := strings.Join(parts[:len(parts)-1], "/")
+
+	collections, err := c.findCollections(baseDoc)
+	if err != nil {
+		return []prompt.Suggest{}
+	}
+	suggestions := make([]prompt.Suggest, 0, len(collections))
+	for _, col := range collections {
+		suggestions = append(suggestions, newCollectionSuggestion(baseDoc, col))
+	}
+	return prompt.FilterHasPrefix(suggestions, literal, false)
+}
+
+func (c *Completer) parseAggregateOperation() ([]prompt.Suggest, error) {
+	if !isName(c.peekToken) {
+		return []prompt.Suggest{}, nil
+	}
+	c.nextToken()
+
+	collectionGroupMode := false
+	if c.curTokenIs(COLLECTION_GROUP) {
+		collectionGroupMode = true
+		if !c.expectPeek(IDENT) {
+			return []prompt.Suggest{}, nil
+		}
+	}
+
+	if c.peekTokenIs(EOF) {
+		if collectionGroupMode {
+			return aggregationSuggestions, nil
+		}
+		collection := normalizeFirestorePath(c.curToken.Literal)
+		parts := strings.Split(collection, "/")
+		if len(parts)%2 == 0 {
+			return []prompt.Suggest{}, nil
+		}
+
+		var baseDoc string
+		if len(parts) == 1 {
+			baseDoc = ""
+		} else {
+			baseDoc = strings.Join(parts[:len(parts)-1], "/")
+		}
+		collections, err := c.findCollections(baseDoc)
+		if err != nil {
+			return []prompt.Suggest{}, nil
+		}
+		suggestions := make([]prompt.Suggest, 0, len(collections))
+		for _, col := range collections {
+			suggestions = append(suggestions, newCollectionSuggestion(baseDoc, col))
+		}
+		suggestions = append(suggestions, collectionGroupSuggestion)
+
+		return prompt.FilterHasPrefix(suggestions, c.curToken.Literal, false), nil
+	}
+
+	c.nextToken()
+
+	if c.curTokenIs(EOF) {
+		return []prompt.Suggest{}, nil
+	}
+
+	if suggestions, ok := c.parseAggregations(); !ok {
+		return suggestions, nil
+	}
+
+	if c.curTokenIs(WHERE) {
+		if suggestions, ok := c.parseWhere(); !ok {
+			return suggestions, nil
+		}
+	}
+
+	return []prompt.Suggest{}, nil
+}
+
+// parseAggregations advances past the aggregations of AGGREGATE.
+// It returns false with suggestions when the input ends inside them.
+func (c *Completer) parseAggregations() ([]prompt.Suggest, bool) {
+	for {
+		if (c.curTokenIs(IDENT) || c.curTokenIs(COUNT)) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix(aggregationSuggestions, c.curToken.Literal, true), false
+		}
+
+		// function(field)
+		for !c.curTokenIs(RPAREN) {
+			if c.curTokenIs(EOF) {
+				return []prompt.Suggest{}, false
+			}
+			c.nextToken()
+		}
+		c.nextToken()
+
+		suggestions := []prompt.Suggest{asSuggestion, whereSuggestion}
+		if c.curTokenIs(AS) {
+			c.nextToken()
+			if c.curTokenIs(EOF) || c.peekTokenIs(EOF) {
+				return []prompt.Suggest{}, false
+			}
+			c.nextToken()
+			suggestions = []prompt.Suggest{whereSuggestion}
+		}
+
+		if c.curTokenIs(IDENT) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix(suggestions, c.curToken.Literal, true), false
+		}
+		if !c.curTokenIs(COMMA) {
+			return []prompt.Suggest{}, !c.curTokenIs(EOF)
+		}
+		c.nextToken()
+	}
+}
+
+// parseWhere advances past the conditions of a WHERE clause.
+// It returns false with suggestions when the input ends inside the clause.
+func (c *Completer) parseWhere() ([]prompt.Suggest, bool) {
+	c.nextToken()
+	for {
+		for c.curTokenIs(LPAREN) {
+			c.nextToken()
+		}
+		if c.curTokenIs(EOF) {
+			return []prompt.Suggest{}, false
+		}
+
+		// field
+		c.nextToken()
+		if (c.curTokenIs(IDENT) || c.curTokenIs(NOT)) && c.peekTokenIs(EOF) {
+			return prompt.FilterHasPrefix(operatorSuggestions, c.curToken.Literal, true), false
+		}
+		if c.curTokenIs(NOT) && c.peekTokenIs(IN) {
+			c.nextToken()
+		} else if c.curTokenIs(IS) {
+			if c.peekTokenIs(NOT) {
 				c.nextToken()
-			} else {
-				break
 			}
+		} else if !c.curTokenIsOperator() {
+			return []prompt.Suggest{}, !c.curTokenIs(EOF)
+		}
+
+		// value
+		c.nextToken()
+		if c.curTokenIs(EOF) {
+			return []prompt.Suggest{}, false
+		}
+		c.skipValue()
+		c.nextToken()
+
+		for c.curTokenIs(RPAREN) {
+			c.nextToken()
 		}
+		if !c.curTokenIs(AND) && !c.curTokenIs(OR) {
+			return []prompt.Suggest{}, !c.curTokenIs(EOF)
+		}
+		c.nextToken()
 	}
+}
 
-	return []prompt.Suggest{}, nil
+// skipCursor advances past START/END AT/AFTER/BEFORE and its values.
+// It returns false when the input ends inside the clause.
+func (c *Completer) skipCursor() bool {
+	c.nextToken()
+	if c.curTokenIs(EOF) {
+		return false
+	}
+	c.nextToken()
+	for !c.curTokenIs(EOF) {
+		c.skipValue()
+		c.nextToken()
+		if !c.curTokenIs(COMMA) {
+			return !c.curTokenIs(EOF)
+		}
+		c.nextToken()
+	}
+	return false
+}
+
+// skipValue moves to the last token of a value such as [1, 2], {a: 1} or
+// TIMESTAMP("...").
+func (c *Completer) skipValue() {
+	var opening, closing TokenType
+	if c.curTokenIs(LBRACKET) {
+		opening, closing = LBRACKET, RBRACKET
+	} else if c.curTokenIs(LBRACE) {
+		opening, closing = LBRACE, RBRACE
+	} else if c.peekTokenIs(LPAREN) {
+		c.nextToken()
+		opening, closing = LPAREN, RPAREN
+	} else {
+		return
+	}
+	depth := 0
+	for {
+		if c.curTokenIs(opening) {
+			depth++
+		} else if c.curTokenIs(closing) {
+			depth--
+		}
+		if depth == 0 || c.peekTokenIs(EOF) {
+			return
+		}
+		c.nextToken()
+	}
 }
 
 func (c *Completer) nextToken() {
@@ -412,10 +1113,23 @@ func (c *Completer) curTokenIsOperator() bool {
 		c.curTokenIs(LT) ||
 		c.curTokenIs(LTE) ||
 		c.curTokenIs(IN) ||
+		c.curTokenIs(NOT_IN) ||
 		c.curTokenIs(ARRAY_CONTAINS) ||
 		c.curTokenIs(ARRAY_CONTAINS_ANY)
 }
 
+// curTokenIsWord reports whether the current token is the given word
+// that is matched by literal rather than as a keyword, such as TO.
+func (c *Completer) curTokenIsWord(word string) bool {
+	return c.curTokenIs(IDENT) && strings.EqualFold(c.curToken.Literal, word)
+}
+
+// curTokenIsFindNearest reports whether FIND starts a FIND NEAREST clause
+// rather than being typed as a clause name.
+func (c *Completer) curTokenIsFindNearest() bool {
+	return c.curTokenIsWord(FIND) && !c.peekTokenIs(EOF)
+}
+
 func (c *Completer) peekTokenIs(t TokenType) bool {
 	return c.peekToken.Type == t
 }
@@ -28,6 +28,11 @@ func TestCompleter(t *testing.T) {
 			input: `GE`,
 			want:  []prompt.Suggest{getSuggestion},
 		},
+		{
+			desc:  "middle of rollback",
+			input: `roll`,
+			want:  []prompt.Suggest{rollbackSuggestion},
+		},
 		{
 			desc:  "query",
 			input: `QUERY`,
@@ -43,6 +48,11 @@ func TestCompleter(t *testing.T) {
 			input: `QUERY us`,
 			want:  []prompt.Suggest{newCollectionSuggestion("", "user")},
 		},
+		{
+			desc:  "middle of query with unicode collection",
+			input: `QUERY ユー`,
+			want:  []prompt.Suggest{newCollectionSuggestion("", "ユーザー")},
+		},
 		{
 			desc:  "middle of query with collection_group keyword",
 			input: `QUERY C`,
@@ -66,7 +76,7 @@ func TestCompleter(t *testing.T) {
 		{
 			desc:  "middle of query with select",
 			input: `QUERY user S`,
-			want:  []prompt.Suggest{selectSuggestion},
+			want:  []prompt.Suggest{selectSuggestion, startAtSuggestion, startAfterSuggestion},
 		},
 		{
 			desc:  "query with select and no field",
@@ -94,11 +104,46 @@ func TestCompleter(t *testing.T) {
 			// TODO: should return operators
 			want: []prompt.Suggest{},
 		},
+		{
+			desc:  "middle of not in operator",
+			input: `QUERY user WHERE age NOT`,
+			want:  []prompt.Suggest{notInSuggestion},
+		},
+		{
+			desc:  "middle of array operator",
+			input: `QUERY user WHERE tags ARR`,
+			want:  []prompt.Suggest{arrayContainsSuggestion, arrayContainsAnySuggestion},
+		},
+		{
+			desc:  "middle of order by after not in",
+			input: `QUERY user WHERE age NOT IN [1, 2] ORD`,
+			want:  []prompt.Suggest{orderBySuggestion},
+		},
+		{
+			desc:  "middle of order by after is not null",
+			input: `QUERY user WHERE deletedAt IS NOT NULL ORD`,
+			want:  []prompt.Suggest{orderBySuggestion},
+		},
 		{
 			desc:  "query with select and field and where and field and operator",
 			input: `QUERY user SELECT name WHERE name ==`,
 			want:  []prompt.Suggest{},
 		},
+		{
+			desc:  "middle of group by after aggregation",
+			input: `QUERY user SELECT status, COUNT(*) AS count, SUM(amount) G`,
+			want:  []prompt.Suggest{groupBySuggestion},
+		},
+		{
+			desc:  "middle of order by after group by",
+			input: `QUERY user SELECT COUNT(*) GROUP BY status, age ORD`,
+			want:  []prompt.Suggest{orderBySuggestion},
+		},
+		{
+			desc:  "middle of limit after group by",
+			input: `QUERY user SELECT COUNT(*) WHERE age > 20 GROUP BY status L`,
+			want:  []prompt.Suggest{limitSuggestion},
+		},
 		{
 			desc:  "middle of order by",
 			input: `QUERY user ORD`,
@@ -109,6 +154,21 @@ func TestCompleter(t *testing.T) {
 			input: `QUERY user SELECT name WHERE name = "Doe" ORD`,
 			want:  []prompt.Suggest{orderBySuggestion},
 		},
+		{
+			desc:  "middle of order by after or",
+			input: `QUERY user WHERE name = "Doe" OR name = "John" ORD`,
+			want:  []prompt.Suggest{orderBySuggestion},
+		},
+		{
+			desc:  "middle of order by after parentheses",
+			input: `QUERY user WHERE (age IN [1, 2] OR name = "Doe") AND age > 1 ORD`,
+			want:  []prompt.Suggest{orderBySuggestion},
+		},
+		{
+			desc:  "query with where and open parenthesis",
+			input: `QUERY user WHERE (`,
+			want:  []prompt.Suggest{},
+		},
 		{
 			desc:  "query with order by",
 			input: `QUERY user ORDER BY`,
@@ -122,7 +182,7 @@ func TestCompleter(t *testing.T) {
 		{
 			desc:  "middle of asc",
 			input: `QUERY user ORDER BY name A`,
-			want:  []prompt.Suggest{ascSuggestion},
+			want:  []prompt.Suggest{ascSuggestion, asOfSuggestion},
 		},
 		{
 			desc:  "middle of desc",
@@ -144,6 +204,56 @@ func TestCompleter(t *testing.T) {
 			input: `QUERY user ORDER BY name ASC, age DESC LI`,
 			want:  []prompt.Suggest{limitSuggestion},
 		},
+		{
+			desc:  "middle of start after order by",
+			input: `QUERY user ORDER BY name DESC ST`,
+			want:  []prompt.Suggest{startAtSuggestion, startAfterSuggestion},
+		},
+		{
+			desc:  "query with start after",
+			input: `QUERY user ORDER BY name START AFTER`,
+			want:  []prompt.Suggest{},
+		},
+		{
+			desc:  "middle of end after start",
+			input: `QUERY user ORDER BY name, age START AFTER "Doe", 20 EN`,
+			want:  []prompt.Suggest{endAtSuggestion, endBeforeSuggestion},
+		},
+		{
+			desc:  "middle of limit after end",
+			input: `QUERY user ORDER BY createdAt END BEFORE TIMESTAMP("2024-01-01") LI`,
+			want:  []prompt.Suggest{limitSuggestion},
+		},
+		{
+			desc:  "middle of end after document cursor",
+			input: `QUERY user START AFTER user/abc E`,
+			want:  []prompt.Suggest{endAtSuggestion, endBeforeSuggestion},
+		},
+		{
+			desc:  "middle of offset",
+			input: `QUERY user WHERE name = "Doe" OFF`,
+			want:  []prompt.Suggest{offsetSuggestion},
+		},
+		{
+			desc:  "middle of limit last",
+			input: `QUERY user ORDER BY name LIMIT LA`,
+			want:  []prompt.Suggest{lastSuggestion},
+		},
+		{
+			desc:  "middle of offset after limit last",
+			input: `QUERY user ORDER BY name LIMIT LAST 10 O`,
+			want:  []prompt.Suggest{offsetSuggestion},
+		},
+		{
+			desc:  "middle of as of after offset",
+			input: `QUERY user LIMIT 10 OFFSET 20 A`,
+			want:  []prompt.Suggest{asOfSuggestion},
+		},
+		{
+			desc:  "middle of as of after limit",
+			input: `QUERY user LIMIT 10 A`,
+			want:  []prompt.Suggest{asOfSuggestion},
+		},
 		{
 			desc:  "middle of get with collection",
 			input: `GET us`,
@@ -157,7 +267,7 @@ func TestCompleter(t *testing.T) {
 		{
 			desc:  "get with doc path suggest select",
 			input: `GET user/1 `,
-			want:  []prompt.Suggest{selectSuggestion},
+			want:  []prompt.Suggest{selectSuggestion, asOfSuggestion},
 		},
 		{
 			desc:  "middle of get select",
@@ -177,7 +287,87 @@ func TestCompleter(t *testing.T) {
 		{
 			desc:  "middle of count",
 			input: `CO`,
-			want:  []prompt.Suggest{countSuggestion},
+			want:  []prompt.Suggest{countSuggestion, copySuggestion, commitSuggestion},
+		},
+		{
+			desc:  "middle of aggregate",
+			input: `AG`,
+			want:  []prompt.Suggest{aggregateSuggestion},
+		},
+		{
+			desc:  "middle of aggregate with collection",
+			input: `AGGREGATE us`,
+			want:  []prompt.Suggest{newCollectionSuggestion("", "user")},
+		},
+		{
+			desc:  "middle of aggregation",
+			input: `AGGREGATE user S`,
+			want:  []prompt.Suggest{sumSuggestion},
+		},
+		{
+			desc:  "middle of second aggregation",
+			input: `AGGREGATE user COUNT(*), A`,
+			want:  []prompt.Suggest{avgSuggestion},
+		},
+		{
+			desc:  "middle of as after aggregation",
+			input: `AGGREGATE user SUM(amount) A`,
+			want:  []prompt.Suggest{asSuggestion},
+		},
+		{
+			desc:  "middle of where after alias",
+			input: `AGGREGATE user SUM(amount) AS total W`,
+			want:  []prompt.Suggest{whereSuggestion},
+		},
+		{
+			desc:  "aggregate with where",
+			input: `AGGREGATE user COUNT(*) WHERE age N`,
+			want:  []prompt.Suggest{notInSuggestion},
+		},
+		{
+			desc:  "query with where suggest find nearest",
+			input: `QUERY user WHERE age > 20 FI`,
+			want:  []prompt.Suggest{findNearestSuggestion},
+		},
+		{
+			desc:  "find nearest suggest measure",
+			input: `QUERY user FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE CO`,
+			want:  []prompt.Suggest{distanceMeasureSuggestions[0]},
+		},
+		{
+			desc:  "find nearest suggest distance field",
+			input: `QUERY user FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE LIMIT 5 D`,
+			want:  []prompt.Suggest{distanceFieldSuggestion},
+		},
+		{
+			desc:  "find nearest with distance field suggest as of",
+			input: `QUERY user FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE LIMIT 5 DISTANCE FIELD d A`,
+			want:  []prompt.Suggest{asOfSuggestion},
+		},
+		{
+			desc:  "middle of explain",
+			input: `EXP`,
+			want:  []prompt.Suggest{explainSuggestion},
+		},
+		{
+			desc:  "middle of explain analyze",
+			input: `EXPLAIN A`,
+			want:  []prompt.Suggest{analyzeSuggestion},
+		},
+		{
+			desc:  "middle of explain query",
+			input: `EXPLAIN ANALYZE Q`,
+			want:  []prompt.Suggest{querySuggestion},
+		},
+		{
+			desc:  "explain query with collection",
+			input: `EXPLAIN QUERY us`,
+			want:  []prompt.Suggest{newCollectionSuggestion("", "user")},
+		},
+		{
+			desc:  "explain analyze query clauses",
+			input: `EXPLAIN ANALYZE QUERY user WHERE age > 20 ORD`,
+			want:  []prompt.Suggest{orderBySuggestion},
 		},
 		{
 			desc:  "count",
@@ -214,16 +404,206 @@ func TestCompleter(t *testing.T) {
 			input: `COUNT user WH`,
 			want:  []prompt.Suggest{whereSuggestion},
 		},
+		{
+			desc:  "middle of as of after count where",
+			input: `COUNT user WHERE age > 20 A`,
+			want:  []prompt.Suggest{asOfSuggestion},
+		},
+		{
+			desc:  "middle of as of after get select",
+			input: `GET user/1 SELECT name, age A`,
+			want:  []prompt.Suggest{asOfSuggestion},
+		},
 		{
 			desc:  "count with where",
 			input: `COUNT user WHERE`,
 			want:  []prompt.Suggest{},
 		},
+		{
+			desc:  "middle of insert",
+			input: `INS`,
+			want:  []prompt.Suggest{insertSuggestion},
+		},
+		{
+			desc:  "middle of into",
+			input: `INSERT IN`,
+			want:  []prompt.Suggest{intoSuggestion},
+		},
+		{
+			desc:  "middle of insert with sub collection",
+			input: `INSERT INTO user/1/p`,
+			want:  []prompt.Suggest{newCollectionSuggestion("user/1", "posts")},
+		},
+		{
+			desc:  "insert with collection",
+			input: `INSERT INTO user V`,
+			want:  []prompt.Suggest{valuesSuggestion},
+		},
+		{
+			desc:  "middle of values after id",
+			input: `INSERT INTO user ID "abc" VA`,
+			want:  []prompt.Suggest{valuesSuggestion},
+		},
+		{
+			desc:  "middle of set with collection",
+			input: `SET us`,
+			want:  []prompt.Suggest{newCollectionSuggestion("", "user")},
+		},
+		{
+			desc:  "middle of merge",
+			input: `SET user/1 {name: "a", address: {city: "b"}} M`,
+			want:  []prompt.Suggest{mergeSuggestion},
+		},
+		{
+			desc:  "middle of if after set merge",
+			input: `SET user/1 {name: "a"} MERGE I`,
+			want:  []prompt.Suggest{ifSuggestion},
+		},
+		{
+			desc:  "middle of precondition",
+			input: `SET user/1 {name: "a"} IF N`,
+			want:  []prompt.Suggest{preconditionSuggestions[1]},
+		},
+		{
+			desc:  "middle of update",
+			input: `UPD`,
+			want:  []prompt.Suggest{updateSuggestion},
+		},
+		{
+			desc:  "middle of update with sub collection",
+			input: `UPDATE user/1/p`,
+			want:  []prompt.Suggest{newCollectionSuggestion("user/1", "posts")},
+		},
+		{
+			desc:  "middle of set after update path",
+			input: `UPDATE user/1 S`,
+			want:  []prompt.Suggest{updateSetSuggestion},
+		},
+		{
+			desc:  "middle of transform",
+			input: `UPDATE user/1 SET name = "a", n = INC`,
+			want:  []prompt.Suggest{transformSuggestions[0]},
+		},
+		{
+			desc:  "middle of where after bulk update",
+			input: `UPDATE user SET a = 1, b = {c: [1, 2]} W`,
+			want:  []prompt.Suggest{whereSuggestion},
+		},
+		{
+			desc:  "middle of limit after bulk update where",
+			input: `UPDATE user SET n = INCREMENT(1) WHERE age > 20 L`,
+			want:  []prompt.Suggest{writeLimitSuggestion},
+		},
+		{
+			desc:  "update document with trailing word",
+			input: `UPDATE user/1 SET a = 1 W`,
+			want:  []prompt.Suggest{},
+		},
+		{
+			desc:  "middle of delete",
+			input: `DEL`,
+			want:  []prompt.Suggest{deleteSuggestion},
+		},
+		{
+			desc:  "middle of from",
+			input: `DELETE FR`,
+			want:  []prompt.Suggest{fromSuggestion},
+		},
+		{
+			desc:  "middle of delete with collection",
+			input: `DELETE FROM us`,
+			want:  []prompt.Suggest{newCollectionSuggestion("", "user")},
+		},
+		{
+			desc:  "middle of where after delete",
+			input: `DELETE FROM user W`,
+			want:  []prompt.Suggest{whereSuggestion},
+		},
+		{
+			desc:  "middle of operator after delete where",
+			input: `DELETE FROM user WHERE tags ARR`,
+			want:  []prompt.Suggest{arrayContainsSuggestion, arrayContainsAnySuggestion},
+		},
+		{
+			desc:  "middle of delete with document path",
+			input: `DELETE user/1/p`,
+			want:  []prompt.Suggest{newCollectionSuggestion("user/1", "posts")},
+		},
+		{
+			desc:  "middle of recursive",
+			input: `DELETE user/1 R`,
+			want:  []prompt.Suggest{recursiveSuggestion},
+		},
+		{
+			desc:  "middle of if after update",
+			input: `UPDATE user/1 SET a = 1 I`,
+			want:  []prompt.Suggest{ifSuggestion},
+		},
+		{
+			desc:  "middle of updated at after delete if",
+			input: `DELETE user/1 IF UP`,
+			want:  []prompt.Suggest{preconditionSuggestions[2]},
+		},
+		{
+			desc:  "middle of copy with collection",
+			input: `COPY us`,
+			want:  []prompt.Suggest{newCollectionSuggestion("", "user")},
+		},
+		{
+			desc:  "middle of collection after copy",
+			input: `COPY COLL`,
+			want:  []prompt.Suggest{collectionSuggestion},
+		},
+		{
+			desc:  "middle of to after copy",
+			input: `COPY user/1 T`,
+			want:  []prompt.Suggest{copyToSuggestion},
+		},
+		{
+			desc:  "middle of copy target",
+			input: `COPY user/2 TO user/1/p`,
+			want:  []prompt.Suggest{newCollectionSuggestion("user/1", "posts")},
+		},
+		{
+			desc:  "middle of recursive after copy",
+			input: `COPY user/1 TO archive/1 R`,
+			want:  []prompt.Suggest{recursiveSuggestion},
+		},
+		{
+			desc:  "middle of where after copy collection",
+			input: `COPY COLLECTION user TO archive W`,
+			want:  []prompt.Suggest{whereSuggestion},
+		},
+		{
+			desc:  "middle of recursive after copy collection where",
+			input: `COPY COLLECTION user TO archive WHERE age > 20 R`,
+			want:  []prompt.Suggest{recursiveSuggestion},
+		},
+		{
+			desc:  "middle of drop",
+			input: `DR`,
+			want:  []prompt.Suggest{dropSuggestion},
+		},
+		{
+			desc:  "middle of drop collection",
+			input: `DROP C`,
+			want:  []prompt.Suggest{collectionSuggestion},
+		},
+		{
+			desc:  "middle of drop with sub collection",
+			input: `DROP COLLECTION user/1/p`,
+			want:  []prompt.Suggest{newCollectionSuggestion("user/1", "posts")},
+		},
+		{
+			desc:  "set inside object",
+			input: `SET user/1 {name: "a", ad`,
+			want:  []prompt.Suggest{},
+		},
 	}
 
 	findCollections := func(baseDoc string) ([]string, error) {
 		if baseDoc == "" {
-			return []string{"user", "group"}, nil
+			return []string{"user", "group", "ユーザー"}, nil
 		}
 		if baseDoc == "user/1" {
 			return []string{"posts"}, nil
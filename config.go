@@ -0,0 +1,45 @@
+package fscli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shibukawa/configdir"
+)
+
+const CONFIG_FILE = "config.json"
+
+// ProjectConfig holds the settings of one Firebase project.
+type ProjectConfig struct {
+	ReadOnly bool `json:"readOnly"`
+}
+
+// Config is the content of the config file, with settings per project ID:
+//
+//	{"projects": {"my-prod": {"readOnly": true}}}
+type Config struct {
+	Projects map[string]ProjectConfig `json:"projects"`
+}
+
+// LoadProjectConfig reads the settings of projectId from the config file
+// in the config folder. Without a config file every setting is off.
+func LoadProjectConfig(projectId string) (ProjectConfig, error) {
+	configDirs := configdir.New(VENDOR_NAME, APP_NAME)
+	folder := configDirs.QueryFolderContainsFile(CONFIG_FILE)
+	if folder == nil {
+		return ProjectConfig{}, nil
+	}
+	data, err := folder.ReadFile(CONFIG_FILE)
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+	return parseProjectConfig(data, projectId)
+}
+
+func parseProjectConfig(data []byte, projectId string) (ProjectConfig, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ProjectConfig{}, fmt.Errorf("invalid %s: %w", CONFIG_FILE, err)
+	}
+	return config.Projects[projectId], nil
+}
@@ -0,0 +1,46 @@
+package fscli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProjectConfig(t *testing.T) {
+	data := []byte(`{"projects": {"prod": {"readOnly": true}, "dev": {}}}`)
+
+	tests := []struct {
+		desc      string
+		projectId string
+		want      ProjectConfig
+	}{
+		{
+			desc:      "read-only project",
+			projectId: "prod",
+			want:      ProjectConfig{ReadOnly: true},
+		},
+		{
+			desc:      "project without settings",
+			projectId: "dev",
+			want:      ProjectConfig{},
+		},
+		{
+			desc:      "unknown project",
+			projectId: "staging",
+			want:      ProjectConfig{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := parseProjectConfig(data, tt.projectId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := parseProjectConfig([]byte(`{"projects": []}`), "prod")
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package fscli
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// copyBatchSize is how many documents are read at a time.
+const copyBatchSize = 500
+
+// CopiedCollection is the number of documents copied into a collection.
+type CopiedCollection struct {
+	Path   string `json:"path"`
+	Copied int    `json:"copied"`
+}
+
+// CopyResult summarizes a COPY. Collections are the target collections in
+// the order their first document was copied.
+type CopyResult struct {
+	Collections []CopiedCollection `json:"collections"`
+	Failures    []BulkWriteFailure `json:"failures"`
+}
+
+// ExecuteCopy copies the documents of op with their data as it is, so
+// timestamps, references and other values keep their types. Documents are
+// created, so a document that already exists at the target fails and is
+// left as it is. Like a bulk UPDATE or DELETE, a collection copy fails
+// before writing anything when it matches more than bulkWriteLimit
+// documents; the documents of their subcollections are not limited, like
+// DELETE ... RECURSIVE. When it fails midway, the documents copied so far
+// are returned along with the error.
+func (exe *Executor) ExecuteCopy(ctx context.Context, op *CopyOperation, progress BulkWriteProgress) (*CopyResult, error) {
+	return exe.copy(ctx, op, progress, false)
+}
+
+// DryRunCopy counts the documents ExecuteCopy would copy per target
+// collection.
+func (exe *Executor) DryRunCopy(ctx context.Context, op *CopyOperation) (*CopyResult, error) {
+	return exe.copy(ctx, op, nil, true)
+}
+
+func (exe *Executor) copy(ctx context.Context, op *CopyOperation, progress BulkWriteProgress, dryRun bool) (*CopyResult, error) {
+	from := exe.fs.Collection(op.Collection())
+	to := exe.fs.Collection(op.ToCollection())
+	if from == nil || to == nil {
+		return nil, ErrInvalidCollection
+	}
+
+	// the sources are read before anything is written, so that a copy
+	// that cannot start has no result
+	var refs []*firestore.DocumentRef
+	var doc *firestore.DocumentSnapshot
+	if op.IsCollection() {
+		var err error
+		refs, err = exe.copyTargets(ctx, from, op.query, op.recursive)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		src := from.Doc(op.docId)
+		docs, err := exe.getAll(ctx, []*firestore.DocumentRef{src})
+		if err != nil {
+			return nil, err
+		}
+		if !docs[0].Exists() {
+			return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, refPath(src))
+		}
+		doc = docs[0]
+	}
+
+	c := exe.newCopier(ctx, op.recursive, progress, dryRun)
+	var err error
+	if op.IsCollection() {
+		err = c.copyRefs(refs, to)
+	} else {
+		err = c.copyDocument(doc, to.Doc(op.toDocId))
+	}
+	return c.end(), err
+}
+
+// copyTargets returns the documents of a collection copy, reading only
+// their names. A recursive copy of a whole collection lists its documents
+// by name, so that subcollections of documents that only exist as their
+// parent are copied too.
+func (exe *Executor) copyTargets(ctx context.Context, from *firestore.CollectionRef, query *QueryOperation, recursive bool) ([]*firestore.DocumentRef, error) {
+	if !recursive || len(query.filters) > 0 || query.limit > 0 {
+		return exe.bulkTargets(ctx, "COPY", query)
+	}
+
+	itr := from.DocumentRefs(ctx)
+	refs := make([]*firestore.DocumentRef, 0)
+	for {
+		ref, err := itr.Next()
+		if err == iterator.Done {
+			return refs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+		if len(refs) > exe.bulkWriteLimit {
+			return nil, fmt.Errorf("%w: COPY writes at most %d documents, narrow it with WHERE or LIMIT", ErrTooManyDocuments, exe.bulkWriteLimit)
+		}
+	}
+}
+
+type copier struct {
+	ctx       context.Context
+	fs        *firestore.Client
+	writes    *writeTracker
+	recursive bool
+}
+
+// newCopier returns a copier. With dryRun it only counts the documents it
+// would copy.
+func (exe *Executor) newCopier(ctx context.Context, recursive bool, progress BulkWriteProgress, dryRun bool) *copier {
+	return &copier{
+		ctx:       ctx,
+		fs:        exe.fs,
+		writes:    exe.newWriteTracker(ctx, progress, dryRun),
+		recursive: recursive,
+	}
+}
+
+// copyDocument writes doc to dst if it exists, then copies the
+// subcollections of doc if the copy is recursive.
+func (c *copier) copyDocument(doc *firestore.DocumentSnapshot, dst *firestore.DocumentRef) error {
+	if doc.Exists() {
+		data := doc.Data()
+		c.writes.write(dst, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+			return bw.Create(ref, data)
+		})
+	}
+	if !c.recursive {
+		return nil
+	}
+
+	itr, err := getCollectionsIterator(c.ctx, c.fs, refPath(doc.Ref))
+	if err != nil {
+		return err
+	}
+	for {
+		col, err := itr.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.copyCollection(col, dst.Collection(col.ID)); err != nil {
+			return err
+		}
+	}
+}
+
+// copyCollection copies every document of src into dst, reading them
+// copyBatchSize at a time.
+func (c *copier) copyCollection(src *firestore.CollectionRef, dst *firestore.CollectionRef) error {
+	itr := src.DocumentRefs(c.ctx)
+	refs := make([]*firestore.DocumentRef, 0, copyBatchSize)
+	for {
+		ref, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		refs = append(refs, ref)
+		if len(refs) == copyBatchSize {
+			if err := c.copyRefs(refs, dst); err != nil {
+				return err
+			}
+			refs = make([]*firestore.DocumentRef, 0, copyBatchSize)
+		}
+	}
+	return c.copyRefs(refs, dst)
+}
+
+// copyRefs copies the documents of refs into dst, reading them
+// copyBatchSize at a time.
+func (c *copier) copyRefs(refs []*firestore.DocumentRef, dst *firestore.CollectionRef) error {
+	for len(refs) > 0 {
+		n := min(len(refs), copyBatchSize)
+		docs, err := c.fs.GetAll(c.ctx, refs[:n])
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if err := c.copyDocument(doc, dst.Doc(doc.Ref.ID)); err != nil {
+				return err
+			}
+		}
+		refs = refs[n:]
+	}
+	return nil
+}
+
+// end sends the remaining writes and returns what was copied.
+func (c *copier) end() *CopyResult {
+	c.writes.end()
+	result := &CopyResult{Collections: make([]CopiedCollection, 0, len(c.writes.counts)), Failures: c.writes.failures}
+	for _, count := range c.writes.counts {
+		result.Collections = append(result.Collections, CopiedCollection{Path: count.path, Copied: count.n})
+	}
+	return result
+}
@@ -0,0 +1,418 @@
+package fscli
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// DocumentChange is a document before and after a write. Before is nil for
+// a document that does not exist yet and After is nil for a deleted one.
+type DocumentChange struct {
+	Path   string         `json:"path"`
+	Before map[string]any `json:"before"`
+	After  map[string]any `json:"after"`
+}
+
+// autoIdPlaceholder stands for the ID Firestore generates on INSERT.
+const autoIdPlaceholder = "(auto ID)"
+
+// DryRunInsert returns the document op would create. It fails like INSERT
+// if the document already exists.
+func (exe *Executor) DryRunInsert(ctx context.Context, op *InsertOperation) ([]DocumentChange, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	data := exe.resolveValue(op.data).(map[string]any)
+
+	if op.docId == "" {
+		return []DocumentChange{{Path: op.Collection() + "/" + autoIdPlaceholder, After: data}}, nil
+	}
+
+	ref := collection.Doc(op.docId)
+	docs, err := exe.getAll(ctx, []*firestore.DocumentRef{ref})
+	if err != nil {
+		return nil, err
+	}
+	if docs[0].Exists() {
+		return nil, fmt.Errorf("%s already exists", refPath(ref))
+	}
+	return []DocumentChange{{Path: refPath(ref), After: data}}, nil
+}
+
+// DryRunSet returns the document op would overwrite or merge into. It
+// fails like SET if the IF clause does not hold.
+func (exe *Executor) DryRunSet(ctx context.Context, op *SetOperation) ([]DocumentChange, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	data := exe.resolveValue(op.data).(map[string]any)
+
+	ref := collection.Doc(op.docId)
+	docs, err := exe.getAll(ctx, []*firestore.DocumentRef{ref})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrecondition(ref, docs[0], op.precondition); err != nil {
+		return nil, err
+	}
+	before := snapshotData(docs[0])
+	after := data
+	if op.merge {
+		after = mergeData(before, data)
+	}
+	return []DocumentChange{{Path: refPath(ref), Before: before, After: after}}, nil
+}
+
+// DryRunUpdate returns the documents op would update. Transforms are
+// applied locally, with the current time for SERVER_TIMESTAMP().
+func (exe *Executor) DryRunUpdate(ctx context.Context, op *UpdateOperation) ([]DocumentChange, error) {
+	var refs []*firestore.DocumentRef
+	if op.IsBulk() {
+		targets, err := exe.bulkTargets(ctx, "UPDATE", op.query)
+		if err != nil {
+			return nil, err
+		}
+		refs = targets
+	} else {
+		collection := exe.fs.Collection(op.Collection())
+		if collection == nil {
+			return nil, ErrInvalidCollection
+		}
+		refs = []*firestore.DocumentRef{collection.Doc(op.docId)}
+	}
+
+	docs, err := exe.getAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	updates := exe.resolveUpdates(op.updates)
+	now := time.Now()
+
+	changes := make([]DocumentChange, 0, len(refs))
+	for i, ref := range refs {
+		if err := checkPrecondition(ref, docs[i], op.precondition); err != nil {
+			return nil, err
+		}
+		if !docs[i].Exists() {
+			if op.IsBulk() {
+				// Deleted since the query ran.
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, refPath(ref))
+		}
+		before := docs[i].Data()
+		after, err := applyUpdates(before, updates, now)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, DocumentChange{Path: refPath(ref), Before: before, After: after})
+	}
+	return changes, nil
+}
+
+// DryRunDelete returns the documents op would delete. Subcollections of a
+// RECURSIVE delete are not listed; see DryRunRecursiveDelete.
+func (exe *Executor) DryRunDelete(ctx context.Context, op *DeleteOperation) ([]DocumentChange, error) {
+	var refs []*firestore.DocumentRef
+	if op.IsBulk() {
+		targets, err := exe.bulkTargets(ctx, "DELETE", op.query)
+		if err != nil {
+			return nil, err
+		}
+		refs = targets
+	} else {
+		collection := exe.fs.Collection(op.Collection())
+		if collection == nil {
+			return nil, ErrInvalidCollection
+		}
+		refs = []*firestore.DocumentRef{collection.Doc(op.docId)}
+	}
+
+	docs, err := exe.getAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]DocumentChange, 0, len(refs))
+	for i, ref := range refs {
+		if err := checkPrecondition(ref, docs[i], op.precondition); err != nil {
+			return nil, err
+		}
+		if !docs[i].Exists() {
+			continue
+		}
+		changes = append(changes, DocumentChange{Path: refPath(ref), Before: docs[i].Data()})
+	}
+	return changes, nil
+}
+
+// DryRunRecursiveDelete counts the documents ExecuteRecursiveDelete would
+// delete per collection.
+func (exe *Executor) DryRunRecursiveDelete(ctx context.Context, op *DeleteOperation) (*RecursiveDeleteResult, error) {
+	d := exe.newRecursiveDeleter(ctx, nil, true)
+	err := d.deleteDocument(exe.fs.Collection(op.Collection()).Doc(op.DocId()))
+	result := d.end()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DryRunDropCollection counts the documents ExecuteDropCollection would
+// delete per collection.
+func (exe *Executor) DryRunDropCollection(ctx context.Context, op *DropCollectionOperation) (*RecursiveDeleteResult, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	d := exe.newRecursiveDeleter(ctx, nil, true)
+	err := d.deleteCollection(collection)
+	result := d.end()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getAll reads the documents of refs in one call. A missing document has
+// a snapshot that does not exist.
+func (exe *Executor) getAll(ctx context.Context, refs []*firestore.DocumentRef) ([]*firestore.DocumentSnapshot, error) {
+	if len(refs) == 0 {
+		return []*firestore.DocumentSnapshot{}, nil
+	}
+	return exe.fs.GetAll(ctx, refs)
+}
+
+// snapshotData returns the data of doc, or nil if it does not exist.
+func snapshotData(doc *firestore.DocumentSnapshot) map[string]any {
+	if !doc.Exists() {
+		return nil
+	}
+	return doc.Data()
+}
+
+// checkPrecondition fails like the write would if doc does not satisfy
+// precondition.
+func checkPrecondition(ref *firestore.DocumentRef, doc *firestore.DocumentSnapshot, precondition *Precondition) error {
+	if precondition == nil || precondition.holds(doc.Exists(), doc.UpdateTime) {
+		return nil
+	}
+	return newPreconditionError(ref, precondition)
+}
+
+// resolveUpdates resolves the REF values of updates, including transform
+// arguments.
+func (exe *Executor) resolveUpdates(updates []Update) []Update {
+	resolved := make([]Update, 0, len(updates))
+	for _, update := range updates {
+		if t, ok := update.Value().(Transform); ok {
+			values := exe.resolveValue(t.Values()).([]any)
+			resolved = append(resolved, NewUpdate(update.FieldName(), NewTransform(t.TransformType(), values)))
+			continue
+		}
+		resolved = append(resolved, NewUpdate(update.FieldName(), exe.resolveValue(update.Value())))
+	}
+	return resolved
+}
+
+// applyUpdates returns a copy of data with updates applied the way
+// Firestore applies them. now is the value of SERVER_TIMESTAMP().
+func applyUpdates(data map[string]any, updates []Update, now time.Time) (map[string]any, error) {
+	after := copyData(data)
+	for _, update := range updates {
+		fp, err := parseFieldPath(update.FieldName())
+		if err != nil {
+			return nil, err
+		}
+		t, isTransform := update.Value().(Transform)
+
+		// Missing or non-map parents become maps, except for a deleted field.
+		parent := after
+		for _, segment := range fp[:len(fp)-1] {
+			child, ok := parent[segment].(map[string]any)
+			if !ok {
+				if isTransform && t.TransformType() == TRANSFORM_DELETE_FIELD {
+					parent = nil
+					break
+				}
+				child = map[string]any{}
+				parent[segment] = child
+			}
+			parent = child
+		}
+		field := fp[len(fp)-1]
+
+		if !isTransform {
+			parent[field] = update.Value()
+			continue
+		}
+		switch t.TransformType() {
+		case TRANSFORM_INCREMENT:
+			parent[field] = increment(parent[field], t.Values()[0])
+		case TRANSFORM_ARRAY_UNION:
+			parent[field] = arrayUnion(parent[field], t.Values())
+		case TRANSFORM_ARRAY_REMOVE:
+			parent[field] = arrayRemove(parent[field], t.Values())
+		case TRANSFORM_DELETE_FIELD:
+			if parent != nil {
+				delete(parent, field)
+			}
+		case TRANSFORM_SERVER_TIMESTAMP:
+			parent[field] = now
+		}
+	}
+	return after, nil
+}
+
+// mergeData returns before with the fields of data set, merging nested maps
+// like SET ... MERGE.
+func mergeData(before map[string]any, data map[string]any) map[string]any {
+	merged := copyData(before)
+	if merged == nil {
+		merged = map[string]any{}
+	}
+	for k, v := range data {
+		if m, ok := v.(map[string]any); ok {
+			if current, ok := merged[k].(map[string]any); ok {
+				merged[k] = mergeData(current, m)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// copyData copies data and its nested maps.
+func copyData(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		if m, ok := v.(map[string]any); ok {
+			v = copyData(m)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// increment adds n to current. A field that is not a number is set to n.
+func increment(current any, n any) any {
+	switch current.(type) {
+	case int64, float64:
+	default:
+		return n
+	}
+	if a, ok := current.(int64); ok {
+		if b, ok := n.(int64); ok {
+			return a + b
+		}
+	}
+	return toFloat64(current) + toFloat64(n)
+}
+
+// arrayUnion appends the values that current does not contain yet. A field
+// that is not an array is replaced.
+func arrayUnion(current any, values []any) []any {
+	array, _ := current.([]any)
+	out := append([]any{}, array...)
+	for _, v := range values {
+		if !containsValue(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// arrayRemove removes every element equal to one of values. A field that
+// is not an array becomes an empty array.
+func arrayRemove(current any, values []any) []any {
+	array, _ := current.([]any)
+	out := []any{}
+	for _, v := range array {
+		if !containsValue(values, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsValue(values []any, v any) bool {
+	for _, item := range values {
+		if sameValue(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue compares Firestore values, document references by path.
+func sameValue(a any, b any) bool {
+	if ra, ok := a.(*firestore.DocumentRef); ok {
+		rb, ok := b.(*firestore.DocumentRef)
+		return ok && refPath(ra) == refPath(rb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// fieldDiff is a field whose value changes. A field that is missing before
+// or after has hasBefore or hasAfter unset.
+type fieldDiff struct {
+	path      string
+	before    any
+	hasBefore bool
+	after     any
+	hasAfter  bool
+}
+
+// diffData lists the changed fields of a document, with nested maps
+// compared field by field. Fields are sorted by path.
+func diffData(before map[string]any, after map[string]any) []fieldDiff {
+	beforeFields := flattenData(before, nil, map[string]any{})
+	afterFields := flattenData(after, nil, map[string]any{})
+
+	paths := make([]string, 0, len(beforeFields)+len(afterFields))
+	for path := range beforeFields {
+		paths = append(paths, path)
+	}
+	for path := range afterFields {
+		if _, ok := beforeFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	diffs := []fieldDiff{}
+	for _, path := range paths {
+		b, hasBefore := beforeFields[path]
+		a, hasAfter := afterFields[path]
+		if hasBefore && hasAfter && sameValue(a, b) {
+			continue
+		}
+		diffs = append(diffs, fieldDiff{path: path, before: b, hasBefore: hasBefore, after: a, hasAfter: hasAfter})
+	}
+	return diffs
+}
+
+// flattenData collects the values of data by field path. Nested maps that
+// are not empty are flattened into their fields.
+func flattenData(data map[string]any, prefix firestore.FieldPath, out map[string]any) map[string]any {
+	for k, v := range data {
+		fp := append(append(firestore.FieldPath{}, prefix...), k)
+		if m, ok := v.(map[string]any); ok && len(m) > 0 {
+			flattenData(m, fp, out)
+			continue
+		}
+		out[fieldPathString(fp)] = v
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package fscli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyUpdates(t *testing.T) {
+	now := time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC)
+	data := map[string]any{
+		"name":    "a",
+		"age":     int64(20),
+		"score":   1.5,
+		"tags":    []any{"x", "y"},
+		"address": map[string]any{"city": "Tokyo", "zip": "100"},
+	}
+
+	tests := []struct {
+		desc    string
+		updates []Update
+		want    map[string]any
+	}{
+		{
+			desc:    "set field",
+			updates: []Update{NewUpdate("name", "b")},
+			want:    map[string]any{"name": "b", "age": int64(20), "score": 1.5, "tags": []any{"x", "y"}, "address": map[string]any{"city": "Tokyo", "zip": "100"}},
+		},
+		{
+			desc:    "set nested field",
+			updates: []Update{NewUpdate("address.city", "Osaka"), NewUpdate("profile.bio", "hi")},
+			want:    map[string]any{"name": "a", "age": int64(20), "score": 1.5, "tags": []any{"x", "y"}, "address": map[string]any{"city": "Osaka", "zip": "100"}, "profile": map[string]any{"bio": "hi"}},
+		},
+		{
+			desc: "increment",
+			updates: []Update{
+				NewUpdate("age", NewTransform(TRANSFORM_INCREMENT, []any{int64(1)})),
+				NewUpdate("score", NewTransform(TRANSFORM_INCREMENT, []any{int64(1)})),
+				NewUpdate("visits", NewTransform(TRANSFORM_INCREMENT, []any{int64(1)})),
+			},
+			want: map[string]any{"name": "a", "age": int64(21), "score": 2.5, "visits": int64(1), "tags": []any{"x", "y"}, "address": map[string]any{"city": "Tokyo", "zip": "100"}},
+		},
+		{
+			desc: "array union and remove",
+			updates: []Update{
+				NewUpdate("tags", NewTransform(TRANSFORM_ARRAY_UNION, []any{"y", "z"})),
+				NewUpdate("tags", NewTransform(TRANSFORM_ARRAY_REMOVE, []any{"x"})),
+			},
+			want: map[string]any{"name": "a", "age": int64(20), "score": 1.5, "tags": []any{"y", "z"}, "address": map[string]any{"city": "Tokyo", "zip": "100"}},
+		},
+		{
+			desc: "delete field and server timestamp",
+			updates: []Update{
+				NewUpdate("address.zip", NewTransform(TRANSFORM_DELETE_FIELD, []any{})),
+				NewUpdate("missing.field", NewTransform(TRANSFORM_DELETE_FIELD, []any{})),
+				NewUpdate("updatedAt", NewTransform(TRANSFORM_SERVER_TIMESTAMP, []any{})),
+			},
+			want: map[string]any{"name": "a", "age": int64(20), "score": 1.5, "tags": []any{"x", "y"}, "address": map[string]any{"city": "Tokyo"}, "updatedAt": now},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := applyUpdates(data, tt.updates, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, "Tokyo", data["address"].(map[string]any)["city"])
+		})
+	}
+}
+
+func TestMergeData(t *testing.T) {
+	before := map[string]any{"name": "a", "address": map[string]any{"city": "Tokyo", "zip": "100"}}
+	got := mergeData(before, map[string]any{"age": int64(20), "address": map[string]any{"city": "Osaka"}})
+	assert.Equal(t, map[string]any{"name": "a", "age": int64(20), "address": map[string]any{"city": "Osaka", "zip": "100"}}, got)
+
+	got = mergeData(nil, map[string]any{"name": "b"})
+	assert.Equal(t, map[string]any{"name": "b"}, got)
+}
+
+func TestDiffData(t *testing.T) {
+	before := map[string]any{"name": "a", "age": int64(20), "address": map[string]any{"city": "Tokyo"}, "a.b": true}
+	after := map[string]any{"name": "a", "age": 20.5, "address": "Osaka", "a.b": false}
+
+	got := diffData(before, after)
+	assert.Equal(t, []fieldDiff{
+		{path: "`a.b`", before: true, hasBefore: true, after: false, hasAfter: true},
+		{path: "address", after: "Osaka", hasAfter: true},
+		{path: "address.city", before: "Tokyo", hasBefore: true},
+		{path: "age", before: int64(20), hasBefore: true, after: 20.5, hasAfter: true},
+	}, got)
+
+	assert.Empty(t, diffData(before, before))
+}
+
+func TestPreconditionHolds(t *testing.T) {
+	updateTime := time.Date(2024, 1, 2, 10, 3, 0, 500, time.UTC)
+
+	tests := []struct {
+		desc         string
+		precondition *Precondition
+		exists       bool
+		updateTime   time.Time
+		want         bool
+	}{
+		{desc: "exists", precondition: NewPrecondition(PRECONDITION_EXISTS, time.Time{}), exists: true, updateTime: updateTime, want: true},
+		{desc: "exists but missing", precondition: NewPrecondition(PRECONDITION_EXISTS, time.Time{}), want: false},
+		{desc: "not exists", precondition: NewPrecondition(PRECONDITION_NOT_EXISTS, time.Time{}), want: true},
+		{desc: "not exists but exists", precondition: NewPrecondition(PRECONDITION_NOT_EXISTS, time.Time{}), exists: true, updateTime: updateTime, want: false},
+		{desc: "updated at", precondition: NewPrecondition(PRECONDITION_UPDATED_AT, updateTime.In(time.FixedZone("+09:00", 9*60*60))), exists: true, updateTime: updateTime, want: true},
+		{desc: "updated later", precondition: NewPrecondition(PRECONDITION_UPDATED_AT, updateTime), exists: true, updateTime: updateTime.Add(time.Microsecond), want: false},
+		{desc: "updated at but missing", precondition: NewPrecondition(PRECONDITION_UPDATED_AT, updateTime), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.precondition.holds(tt.exists, tt.updateTime))
+		})
+	}
+}
@@ -3,84 +3,325 @@ package fscli
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Executor struct {
-	fs *firestore.Client
+	fs               *firestore.Client
+	groupByScanLimit int
+	bulkWriteLimit   int
+	// tx is the transaction GET and QUERY read in, if any.
+	tx *firestore.Transaction
 }
 
-var ErrInvalidCollection = errors.New("invalid collection")
+var (
+	ErrInvalidCollection = errors.New("invalid collection")
+	ErrDocumentNotFound  = errors.New("document not found")
+	ErrTooManyDocuments  = errors.New("too many documents")
+	ErrNotInTransaction  = errors.New("not supported in a transaction")
+	ErrInvalidDocumentID = errors.New("__id__ values must be strings")
+
+	// ErrPreconditionFailed is returned when the IF clause of a write does
+	// not hold.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
 
 func NewExecutor(ctx context.Context, fs *firestore.Client) *Executor {
-	return &Executor{fs}
+	return &Executor{fs: fs, groupByScanLimit: DefaultGroupByScanLimit, bulkWriteLimit: DefaultBulkWriteLimit}
+}
+
+// withTransaction returns a copy of exe whose GET and QUERY read in tx.
+func (exe *Executor) withTransaction(tx *firestore.Transaction) *Executor {
+	e := *exe
+	e.tx = tx
+	return &e
 }
 
 func (exe *Executor) ExecuteQuery(ctx context.Context, op *QueryOperation) ([]*firestore.DocumentSnapshot, error) {
+	q, err := exe.buildQuery(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	itr, err := exe.documents(ctx, q, op)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Stop()
+
+	docs := make([]*firestore.DocumentSnapshot, 0)
+	for {
+		doc, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// ExecuteGroupBy streams the documents of op and aggregates them per group
+// on the client. It fails once more than groupByScanLimit documents are read.
+func (exe *Executor) ExecuteGroupBy(ctx context.Context, op *QueryOperation) ([]map[string]any, error) {
+	g, err := newGrouper(op)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := exe.buildQuery(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	itr, err := exe.documents(ctx, q, op)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Stop()
+
+	scanned := 0
+	for {
+		doc, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanned++
+		if scanned > exe.groupByScanLimit {
+			return nil, fmt.Errorf("%w: GROUP BY reads at most %d documents, narrow it with WHERE or LIMIT", ErrTooManyDocuments, exe.groupByScanLimit)
+		}
+		g.add(doc.Ref.ID, doc.Data())
+	}
+	return g.rows(), nil
+}
+
+func (exe *Executor) buildQuery(ctx context.Context, op *QueryOperation) (firestore.Query, error) {
 	var q firestore.Query
 	if op.IsCollectionGroup() {
 		q = exe.fs.CollectionGroup(op.Collection()).Query
 	} else {
 		collection := exe.fs.Collection(op.Collection())
 		if collection == nil {
-			return nil, ErrInvalidCollection
+			return firestore.Query{}, ErrInvalidCollection
 		}
 		q = collection.Query
 	}
 
 	for _, filter := range op.filters {
-		fieldName := filter.FieldName()
-		value := filter.Value()
-		if fieldName == FieldDocumentID {
-			fieldName = firestore.DocumentID
-			if !op.IsCollectionGroup() {
-				value = toDocRefValue(exe.fs.Collection(op.Collection()), value)
-			}
+		ef, err := exe.toEntityFilter(op.Collection(), op.IsCollectionGroup(), filter)
+		if err != nil {
+			return firestore.Query{}, err
 		}
-		q = q.Where(fieldName, string(filter.Operator()), value)
+		q = q.WhereEntity(ef)
 	}
 
-	if len(op.selects) > 0 {
-		q = q.Select(op.selects...)
+	selects := op.selects
+	if op.IsGroupBy() {
+		selects = groupByFields(op)
+	}
+	if len(selects) > 0 {
+		paths, err := toFieldPaths(selects)
+		if err != nil {
+			return firestore.Query{}, err
+		}
+		q = q.SelectPaths(paths...)
 	}
 
 	if len(op.orderBys) > 0 {
 		for _, orderBy := range op.orderBys {
-			q = q.OrderBy(orderBy.field, firestore.Direction(orderBy.direction))
+			fp, err := toFieldPath(orderBy.field)
+			if err != nil {
+				return firestore.Query{}, err
+			}
+			q = q.OrderByPath(fp, firestore.Direction(orderBy.direction))
+		}
+	}
+
+	if op.startCursor != nil {
+		values, err := exe.cursorValues(ctx, op, op.startCursor)
+		if err != nil {
+			return firestore.Query{}, err
+		}
+		if op.startCursor.inclusive {
+			q = q.StartAt(values...)
+		} else {
+			q = q.StartAfter(values...)
+		}
+	}
+
+	if op.endCursor != nil {
+		values, err := exe.cursorValues(ctx, op, op.endCursor)
+		if err != nil {
+			return firestore.Query{}, err
+		}
+		if op.endCursor.inclusive {
+			q = q.EndAt(values...)
+		} else {
+			q = q.EndBefore(values...)
 		}
 	}
 
 	if op.limit > 0 {
-		q = q.Limit(op.limit)
+		if op.limitToLast {
+			q = q.LimitToLast(op.limit)
+		} else {
+			q = q.Limit(op.limit)
+		}
 	}
 
-	itr := q.Documents(ctx)
+	if op.offset > 0 {
+		q = q.Offset(op.offset)
+	}
+
+	if !op.asOf.IsZero() {
+		q = *q.WithReadOptions(firestore.ReadTime(op.asOf))
+	}
+
+	return q, nil
+}
+
+// documents runs q, as a vector search when op has FIND NEAREST.
+func (exe *Executor) documents(ctx context.Context, q firestore.Query, op *QueryOperation) (*firestore.DocumentIterator, error) {
+	fn := op.findNearest
+	if exe.tx != nil {
+		if fn != nil {
+			return nil, fmt.Errorf("FIND NEAREST is %w", ErrNotInTransaction)
+		}
+		return exe.tx.Documents(q), nil
+	}
+	if fn == nil {
+		return q.Documents(ctx), nil
+	}
+
+	fp, err := parseFieldPath(fn.field)
+	if err != nil {
+		return nil, err
+	}
+	var options *firestore.FindNearestOptions
+	if fn.distanceField != "" {
+		options = &firestore.FindNearestOptions{DistanceResultField: fn.distanceField}
+	}
+	return q.FindNearestPath(fp, fn.vector, fn.limit, fn.measure, options).Documents(ctx), nil
+}
+
+// ExecuteExplain plans the query of op and returns its explain metrics.
+// With ANALYZE the query is run and its results are discarded.
+func (exe *Executor) ExecuteExplain(ctx context.Context, op *ExplainOperation) (*firestore.ExplainMetrics, error) {
+	q, err := exe.buildQuery(ctx, op.Query())
+	if err != nil {
+		return nil, err
+	}
+	q = q.WithRunOptions(firestore.ExplainOptions{Analyze: op.IsAnalyze()})
+
+	itr, err := exe.documents(ctx, q, op.Query())
+	if err != nil {
+		return nil, err
+	}
 	defer itr.Stop()
 
-	docs := make([]*firestore.DocumentSnapshot, 0)
 	for {
-		doc, err := itr.Next()
+		_, err := itr.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
 			return nil, err
 		}
+	}
+	return itr.ExplainMetrics()
+}
 
-		docs = append(docs, doc)
+// cursorValues returns the arguments for Query.StartAt and friends.
+// A document cursor is resolved to its snapshot; a bare ID refers to
+// a document in the queried collection.
+func (exe *Executor) cursorValues(ctx context.Context, op *QueryOperation, cursor *Cursor) ([]any, error) {
+	if cursor.docPath == "" {
+		values := make([]any, 0, len(cursor.values))
+		for _, v := range cursor.values {
+			values = append(values, exe.resolveValue(v))
+		}
+		return values, nil
 	}
-	return docs, nil
+
+	var ref *firestore.DocumentRef
+	if strings.Contains(cursor.docPath, "/") {
+		ref = exe.fs.Doc(cursor.docPath)
+	} else if !op.IsCollectionGroup() {
+		ref = exe.fs.Collection(op.Collection()).Doc(cursor.docPath)
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("invalid cursor document: %s", cursor.docPath)
+	}
+	if !op.asOf.IsZero() {
+		ref = ref.WithReadOptions(firestore.ReadTime(op.asOf))
+	}
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []any{doc}, nil
 }
 
 func (exe *Executor) ExecuteGet(ctx context.Context, op *GetOperation) (*firestore.DocumentSnapshot, error) {
-	doc, err := exe.fs.Collection(op.Collection()).Doc(op.DocId()).Get(ctx)
+	ref := exe.fs.Collection(op.Collection()).Doc(op.DocId())
+	if !op.asOf.IsZero() {
+		ref = ref.WithReadOptions(firestore.ReadTime(op.asOf))
+	}
+	if len(op.Selects()) == 0 {
+		var doc *firestore.DocumentSnapshot
+		var err error
+		if exe.tx != nil {
+			doc, err = exe.tx.Get(ref)
+		} else {
+			doc, err = ref.Get(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+
+	// DocumentRef.Get has no field mask, so selects are applied by
+	// a projection query on the document name.
+	paths, err := toFieldPaths(op.Selects())
+	if err != nil {
+		return nil, err
+	}
+	q := ref.Parent.Query.
+		WhereEntity(firestore.PropertyPathFilter{Path: firestore.FieldPath{firestore.DocumentID}, Operator: "==", Value: ref}).
+		SelectPaths(paths...).
+		Limit(1)
+	if !op.asOf.IsZero() {
+		q = *q.WithReadOptions(firestore.ReadTime(op.asOf))
+	}
+	itr := q.Documents(ctx)
+	if exe.tx != nil {
+		itr = exe.tx.Documents(q)
+	}
+	docs, err := itr.GetAll()
 	if err != nil {
 		return nil, err
 	}
-	return doc, nil
+	if len(docs) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+	return docs[0], nil
 }
 
 func (exe *Executor) ExecuteCount(ctx context.Context, op *CountOperation) (int64, error) {
@@ -96,15 +337,15 @@ func (exe *Executor) ExecuteCount(ctx context.Context, op *CountOperation) (int6
 	}
 
 	for _, filter := range op.filters {
-		fieldName := filter.FieldName()
-		value := filter.Value()
-		if fieldName == FieldDocumentID {
-			fieldName = firestore.DocumentID
-			if !op.IsCollectionGroup() {
-				value = toDocRefValue(exe.fs.Collection(op.Collection()), value)
-			}
+		ef, err := exe.toEntityFilter(op.Collection(), op.IsCollectionGroup(), filter)
+		if err != nil {
+			return 0, err
 		}
-		q = q.Where(fieldName, string(filter.Operator()), value)
+		q = q.WhereEntity(ef)
+	}
+
+	if !op.asOf.IsZero() {
+		return countAsOf(ctx, q, op.asOf)
 	}
 
 	aggrQ := q.NewAggregationQuery().WithCount("all")
@@ -121,23 +362,387 @@ func (exe *Executor) ExecuteCount(ctx context.Context, op *CountOperation) (int6
 	return v.GetIntegerValue(), nil
 }
 
+// countAsOf counts the documents of q at a past read time. Aggregation
+// queries cannot be read at a past time, so the document names are
+// streamed and counted instead.
+func countAsOf(ctx context.Context, q firestore.Query, asOf time.Time) (int64, error) {
+	q = q.Select()
+	itr := q.WithReadOptions(firestore.ReadTime(asOf)).Documents(ctx)
+	defer itr.Stop()
+
+	var count int64
+	for {
+		_, err := itr.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ExecuteAggregate runs the aggregations of op in a single aggregation
+// query and returns the results keyed by alias.
+func (exe *Executor) ExecuteAggregate(ctx context.Context, op *AggregateOperation) (map[string]any, error) {
+	var q firestore.Query
+	if op.IsCollectionGroup() {
+		q = exe.fs.CollectionGroup(op.Collection()).Query
+	} else {
+		collection := exe.fs.Collection(op.Collection())
+		if collection == nil {
+			return nil, ErrInvalidCollection
+		}
+		q = collection.Query
+	}
+
+	for _, filter := range op.filters {
+		ef, err := exe.toEntityFilter(op.Collection(), op.IsCollectionGroup(), filter)
+		if err != nil {
+			return nil, err
+		}
+		q = q.WhereEntity(ef)
+	}
+
+	// User aliases may not be valid Firestore aliases, so the query uses
+	// positional ones.
+	aggrQ := q.NewAggregationQuery()
+	for i, aggregate := range op.aggregates {
+		alias := fmt.Sprintf("aggregate_%d", i)
+		switch aggregate.Function() {
+		case AGGREGATE_COUNT:
+			aggrQ = aggrQ.WithCount(alias)
+		case AGGREGATE_SUM, AGGREGATE_AVG:
+			fp, err := toFieldPath(aggregate.FieldName())
+			if err != nil {
+				return nil, err
+			}
+			if aggregate.Function() == AGGREGATE_SUM {
+				aggrQ = aggrQ.WithSumPath(fp, alias)
+			} else {
+				aggrQ = aggrQ.WithAvgPath(fp, alias)
+			}
+		}
+	}
+
+	results, err := aggrQ.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(op.aggregates))
+	for i, aggregate := range op.aggregates {
+		result, ok := results[fmt.Sprintf("aggregate_%d", i)]
+		if !ok {
+			return nil, errors.New("invalid aggregation result")
+		}
+		v, ok := result.(*firestorepb.Value)
+		if !ok {
+			return nil, errors.New("invalid aggregation result")
+		}
+		values[aggregate.Alias()] = aggregateValue(v)
+	}
+	return values, nil
+}
+
+// WriteResult is a written document.
+type WriteResult struct {
+	Path       string    `json:"path"`
+	UpdateTime time.Time `json:"updateTime"`
+}
+
+func newWriteResult(ref *firestore.DocumentRef, wr *firestore.WriteResult) *WriteResult {
+	return &WriteResult{Path: refPath(ref), UpdateTime: wr.UpdateTime}
+}
+
+// ExecuteInsert creates a document. It fails if a document with the given
+// ID already exists.
+func (exe *Executor) ExecuteInsert(ctx context.Context, op *InsertOperation) (*WriteResult, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	data := exe.resolveValue(op.data).(map[string]any)
+
+	if op.docId == "" {
+		ref, wr, err := collection.Add(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		return newWriteResult(ref, wr), nil
+	}
+
+	ref := collection.Doc(op.docId)
+	wr, err := ref.Create(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return newWriteResult(ref, wr), nil
+}
+
+func (exe *Executor) ExecuteSet(ctx context.Context, op *SetOperation) (*WriteResult, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	data := exe.resolveValue(op.data).(map[string]any)
+
+	ref := collection.Doc(op.docId)
+	var opts []firestore.SetOption
+	if op.merge {
+		opts = append(opts, firestore.MergeAll)
+	}
+	if op.precondition != nil {
+		return exe.setIf(ctx, ref, data, opts, op.precondition)
+	}
+	wr, err := ref.Set(ctx, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newWriteResult(ref, wr), nil
+}
+
+// setIf sets a document if precondition holds. Set takes no
+// preconditions, so IF NOT EXISTS creates the document and the others are
+// checked in a transaction.
+func (exe *Executor) setIf(ctx context.Context, ref *firestore.DocumentRef, data map[string]any, opts []firestore.SetOption, precondition *Precondition) (*WriteResult, error) {
+	if precondition.preconditionType == PRECONDITION_NOT_EXISTS {
+		wr, err := ref.Create(ctx, data)
+		if err != nil {
+			return nil, preconditionError(ref, precondition, err)
+		}
+		return newWriteResult(ref, wr), nil
+	}
+
+	var commit firestore.CommitResponse
+	err := exe.fs.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err := checkPrecondition(ref, doc, precondition); err != nil {
+			return err
+		}
+		return tx.Set(ref, data, opts...)
+	}, firestore.WithCommitResponseTo(&commit))
+	if err != nil {
+		return nil, err
+	}
+	return &WriteResult{Path: refPath(ref), UpdateTime: commit.CommitTime()}, nil
+}
+
+// ExecuteUpdate changes fields of a document. It fails if the document
+// does not exist.
+func (exe *Executor) ExecuteUpdate(ctx context.Context, op *UpdateOperation) (*WriteResult, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+	updates, err := exe.toUpdates(op.updates)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := collection.Doc(op.docId)
+	wr, err := ref.Update(ctx, updates, toPreconditions(op.precondition, true)...)
+	if err != nil {
+		return nil, preconditionError(ref, op.precondition, err)
+	}
+	return newWriteResult(ref, wr), nil
+}
+
+// ExecuteDelete deletes a document. Deleting a missing document succeeds.
+func (exe *Executor) ExecuteDelete(ctx context.Context, op *DeleteOperation) (*WriteResult, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return nil, ErrInvalidCollection
+	}
+
+	ref := collection.Doc(op.docId)
+	wr, err := ref.Delete(ctx, toPreconditions(op.precondition, false)...)
+	if err != nil {
+		return nil, preconditionError(ref, op.precondition, err)
+	}
+	return newWriteResult(ref, wr), nil
+}
+
+// toPreconditions maps the IF clause of UPDATE or DELETE to Firestore
+// preconditions. Update always requires the document to exist, so IF
+// EXISTS adds nothing to it.
+func toPreconditions(precondition *Precondition, update bool) []firestore.Precondition {
+	if precondition == nil {
+		return nil
+	}
+	switch precondition.preconditionType {
+	case PRECONDITION_EXISTS:
+		if update {
+			return nil
+		}
+		return []firestore.Precondition{firestore.Exists}
+	case PRECONDITION_UPDATED_AT:
+		return []firestore.Precondition{firestore.LastUpdateTime(precondition.updateTime)}
+	}
+	return nil
+}
+
+// preconditionError replaces err with ErrPreconditionFailed when Firestore
+// rejected a write to ref because precondition did not hold.
+func preconditionError(ref *firestore.DocumentRef, precondition *Precondition, err error) error {
+	if precondition == nil {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.NotFound, codes.AlreadyExists, codes.FailedPrecondition:
+		return newPreconditionError(ref, precondition)
+	}
+	return err
+}
+
+func newPreconditionError(ref *firestore.DocumentRef, precondition *Precondition) error {
+	return fmt.Errorf("%w: %s %s", ErrPreconditionFailed, refPath(ref), precondition)
+}
+
+func (exe *Executor) toUpdates(updates []Update) ([]firestore.Update, error) {
+	result := make([]firestore.Update, 0, len(updates))
+	for _, update := range updates {
+		fp, err := parseFieldPath(update.FieldName())
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if t, ok := update.Value().(Transform); ok {
+			value = exe.toTransformValue(t)
+		} else {
+			value = exe.resolveValue(update.Value())
+		}
+		result = append(result, firestore.Update{FieldPath: fp, Value: value})
+	}
+	return result, nil
+}
+
+func (exe *Executor) toTransformValue(t Transform) any {
+	values := exe.resolveValue(t.Values()).([]any)
+	switch t.TransformType() {
+	case TRANSFORM_INCREMENT:
+		return firestore.Increment(values[0])
+	case TRANSFORM_ARRAY_UNION:
+		return firestore.ArrayUnion(values...)
+	case TRANSFORM_ARRAY_REMOVE:
+		return firestore.ArrayRemove(values...)
+	case TRANSFORM_DELETE_FIELD:
+		return firestore.Delete
+	default:
+		return firestore.ServerTimestamp
+	}
+}
+
 func (exe *Executor) ExecuteListCollections(ctx context.Context, cmd *MetacommandListCollections) ([]string, error) {
 	return findAllCollections(ctx, exe.fs, cmd.baseDoc)
 }
 
-func toDocRefValue(collection *firestore.CollectionRef, value any) any {
+func (exe *Executor) toEntityFilter(collection string, collectionGroup bool, filter Filter) (firestore.EntityFilter, error) {
+	if f, ok := filter.(*CompositeFilter); ok {
+		filters := make([]firestore.EntityFilter, 0, len(f.Filters()))
+		for _, child := range f.Filters() {
+			ef, err := exe.toEntityFilter(collection, collectionGroup, child)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, ef)
+		}
+		if f.Operator() == OPERATOR_OR {
+			return firestore.OrFilter{Filters: filters}, nil
+		}
+		return firestore.AndFilter{Filters: filters}, nil
+	}
+
+	fp, err := toFieldPath(filter.FieldName())
+	if err != nil {
+		return nil, err
+	}
+	value := exe.resolveValue(filter.Value())
+	if filter.FieldName() == FieldDocumentID && !collectionGroup {
+		value, err = toDocRefValue(exe.fs.Collection(collection), value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return firestore.PropertyPathFilter{Path: fp, Operator: string(filter.Operator()), Value: value}, nil
+}
+
+func toFieldPath(field string) (firestore.FieldPath, error) {
+	if field == FieldDocumentID {
+		return firestore.FieldPath{firestore.DocumentID}, nil
+	}
+	return parseFieldPath(field)
+}
+
+func toFieldPaths(fields []string) ([]firestore.FieldPath, error) {
+	paths := make([]firestore.FieldPath, 0, len(fields))
+	for _, field := range fields {
+		fp, err := toFieldPath(field)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, fp)
+	}
+	return paths, nil
+}
+
+// resolveValue turns REF values, also inside arrays and maps, into document
+// references.
+func (exe *Executor) resolveValue(value any) any {
+	switch v := value.(type) {
+	case Ref:
+		return exe.fs.Doc(v.path)
+	case []any:
+		values := make([]any, 0, len(v))
+		for _, item := range v {
+			values = append(values, exe.resolveValue(item))
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k, item := range v {
+			values[k] = exe.resolveValue(item)
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+func toDocRefValue(collection *firestore.CollectionRef, value any) (any, error) {
 	switch v := value.(type) {
 	case string:
-		return collection.Doc(v)
+		return collection.Doc(v), nil
 	case []any:
 		refs := make([]*firestore.DocumentRef, len(v))
 		for i, item := range v {
-			if s, ok := item.(string); ok {
-				refs[i] = collection.Doc(s)
+			switch item := item.(type) {
+			case string:
+				refs[i] = collection.Doc(item)
+			case *firestore.DocumentRef:
+				refs[i] = item
+			default:
+				return nil, ErrInvalidDocumentID
 			}
 		}
-		return refs
+		return refs, nil
 	default:
-		return value
+		return value, nil
+	}
+}
+
+func aggregateValue(v *firestorepb.Value) any {
+	switch v.GetValueType().(type) {
+	case *firestorepb.Value_IntegerValue:
+		return v.GetIntegerValue()
+	case *firestorepb.Value_DoubleValue:
+		return v.GetDoubleValue()
+	default:
+		return nil
 	}
 }
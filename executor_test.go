@@ -2,7 +2,9 @@ package fscli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
 	"testing"
@@ -11,6 +13,7 @@ import (
 	"cloud.google.com/go/firestore"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 func seed(c *firestore.Client) error {
@@ -222,6 +225,32 @@ func TestQuery(t *testing.T) {
 				{"name": "user-1", "age": int64(21), "nicknames": []any{"u-1-1", "u-1-2"}},
 			},
 		},
+		{
+			desc: "query with or",
+			input: &QueryOperation{collection: "users", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewStringFilter("name", "==", "user-1"),
+					NewIntFilter("age", "==", 23),
+				}),
+			}},
+			want: []map[string]any{
+				{"name": "user-1", "age": int64(21), "nicknames": []any{"u-1-1", "u-1-2"}},
+				{"name": "user-3", "age": int64(23), "nicknames": []any{"u-3-1", "u-3-2"}},
+			},
+		},
+		{
+			desc: "query with or and and",
+			input: &QueryOperation{collection: "users", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewStringFilter("name", "==", "user-1"),
+					NewStringFilter("name", "==", "user-2"),
+				}),
+				NewIntFilter("age", ">", 21),
+			}},
+			want: []map[string]any{
+				{"name": "user-2", "age": int64(22), "nicknames": []any{"u-2-1", "u-2-2"}},
+			},
+		},
 		{
 			desc: "query with IN",
 			input: &QueryOperation{collection: "users", filters: []Filter{
@@ -233,6 +262,25 @@ func TestQuery(t *testing.T) {
 				{"name": "user-2", "age": int64(22), "nicknames": []any{"u-2-1", "u-2-2"}},
 			},
 		},
+		{
+			desc: "query with NOT_IN",
+			input: &QueryOperation{collection: "users", filters: []Filter{
+				NewArrayFilter("age", "not-in", []any{20, 21, 22}),
+			}},
+			want: []map[string]any{
+				{"name": "user-3", "age": int64(23), "nicknames": []any{"u-3-1", "u-3-2"}},
+				{"name": "user-4", "age": int64(24), "nicknames": []any{"u-4-1", "u-4-2"}},
+			},
+		},
+		{
+			desc: "query with __id__ NOT_IN",
+			input: &QueryOperation{collection: "users", filters: []Filter{
+				NewArrayFilter(FieldDocumentID, "not-in", []any{"0", "1", "2", "3"}),
+			}},
+			want: []map[string]any{
+				{"name": "user-4", "age": int64(24), "nicknames": []any{"u-4-1", "u-4-2"}},
+			},
+		},
 		{
 			desc: "query with array-contains",
 			input: &QueryOperation{collection: "users", filters: []Filter{
@@ -306,6 +354,23 @@ func TestQuery(t *testing.T) {
 				{"name": "user-0", "age": int64(20), "nicknames": []any{"u-0-1", "u-0-2"}},
 			},
 		},
+		{
+			desc: "query with start after and end at",
+			input: &QueryOperation{collection: "users", orderBys: []OrderBy{{"age", firestore.Asc}},
+				startCursor: NewValuesCursor(false, []any{int64(21)}), endCursor: NewValuesCursor(true, []any{int64(23)})},
+			want: []map[string]any{
+				{"name": "user-2", "age": int64(22), "nicknames": []any{"u-2-1", "u-2-2"}},
+				{"name": "user-3", "age": int64(23), "nicknames": []any{"u-3-1", "u-3-2"}},
+			},
+		},
+		{
+			desc: "query with document cursor",
+			input: &QueryOperation{collection: "users", orderBys: []OrderBy{{"age", firestore.Desc}},
+				startCursor: NewDocumentCursor(true, "1"), endCursor: NewDocumentCursor(false, "users/0")},
+			want: []map[string]any{
+				{"name": "user-1", "age": int64(21), "nicknames": []any{"u-1-1", "u-1-2"}},
+			},
+		},
 		{
 			desc:  "query with limit",
 			input: &QueryOperation{collection: "users", limit: 2},
@@ -314,6 +379,22 @@ func TestQuery(t *testing.T) {
 				{"name": "user-1", "age": int64(21), "nicknames": []any{"u-1-1", "u-1-2"}},
 			},
 		},
+		{
+			desc:  "query with limit and offset",
+			input: &QueryOperation{collection: "users", orderBys: []OrderBy{{"age", firestore.Asc}}, limit: 2, offset: 1},
+			want: []map[string]any{
+				{"name": "user-1", "age": int64(21), "nicknames": []any{"u-1-1", "u-1-2"}},
+				{"name": "user-2", "age": int64(22), "nicknames": []any{"u-2-1", "u-2-2"}},
+			},
+		},
+		{
+			desc:  "query with limit to last",
+			input: &QueryOperation{collection: "users", orderBys: []OrderBy{{"age", firestore.Asc}}, limit: 2, limitToLast: true},
+			want: []map[string]any{
+				{"name": "user-3", "age": int64(23), "nicknames": []any{"u-3-1", "u-3-2"}},
+				{"name": "user-4", "age": int64(24), "nicknames": []any{"u-4-1", "u-4-2"}},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -332,6 +413,105 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+func TestQueryLiterals(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-query-literals")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	items := map[string]map[string]any{
+		"a": {"active": true, "deletedAt": nil, "score": 1.5, "owner": fs.Doc("users/1"), "location": &latlng.LatLng{Latitude: 35.6, Longitude: 139.7}, "hash": []byte{1, 2, 3}},
+		"b": {"active": false, "deletedAt": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "score": math.NaN(), "owner": fs.Doc("users/2"), "location": &latlng.LatLng{Latitude: 34.7, Longitude: 135.5}, "hash": []byte{4, 5}},
+	}
+	for id, data := range items {
+		if _, err := fs.Collection("items").Doc(id).Set(ctx, data); err != nil {
+			t.Fatal(err)
+		}
+		defer fs.Collection("items").Doc(id).Delete(ctx)
+	}
+
+	tests := []struct {
+		desc  string
+		input *QueryOperation
+		want  []string
+	}{
+		{
+			desc: "query with bool",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewBoolFilter("active", OPERATOR_EQ, true),
+			}},
+			want: []string{"a"},
+		},
+		{
+			desc: "query with null",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewNullFilter("deletedAt", OPERATOR_EQ),
+			}},
+			want: []string{"a"},
+		},
+		{
+			desc: "query with not null",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewNullFilter("deletedAt", OPERATOR_NOT_EQ),
+			}},
+			want: []string{"b"},
+		},
+		{
+			desc: "query with nan",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewNaNFilter("score", OPERATOR_EQ),
+			}},
+			want: []string{"b"},
+		},
+		{
+			desc: "query with ref",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewRefFilter("owner", OPERATOR_EQ, NewRef("users/2")),
+			}},
+			want: []string{"b"},
+		},
+		{
+			desc: "query with refs in array",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewArrayFilter("owner", OPERATOR_IN, []any{NewRef("users/1"), NewRef("users/3")}),
+			}},
+			want: []string{"a"},
+		},
+		{
+			desc: "query with geopoint",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewGeoPointFilter("location", OPERATOR_EQ, &latlng.LatLng{Latitude: 35.6, Longitude: 139.7}),
+			}},
+			want: []string{"a"},
+		},
+		{
+			desc: "query with bytes",
+			input: &QueryOperation{collection: "items", filters: []Filter{
+				NewBytesFilter("hash", OPERATOR_EQ, []byte{4, 5}),
+			}},
+			want: []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			docs, err := exe.ExecuteQuery(ctx, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make([]string, 0)
+			for _, doc := range docs {
+				ids = append(ids, doc.Ref.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
 func TestInvalidQuery(t *testing.T) {
 	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
 	ctx := context.Background()
@@ -364,6 +544,13 @@ func TestInvalidQuery(t *testing.T) {
 			input: &QueryOperation{collection: "users" + "/abc"},
 			err:   ErrInvalidCollection,
 		},
+		{
+			desc: "query with non-string __id__ in",
+			input: &QueryOperation{collection: "users", filters: []Filter{
+				NewArrayFilter(FieldDocumentID, OPERATOR_IN, []any{int64(1), "a"}),
+			}},
+			err: ErrInvalidDocumentID,
+		},
 	}
 
 	for _, tt := range tests {
@@ -408,6 +595,13 @@ func TestGet(t *testing.T) {
 				"name": "user-1", "age": int64(21), "nicknames": []any{"u-1-1", "u-1-2"},
 			},
 		},
+		{
+			desc:  "get with select",
+			input: NewGetOperation("users", "1", []string{"name"}),
+			want: map[string]any{
+				"name": "user-1",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -421,6 +615,381 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestInsertAndSet(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+	defer fs.Collection("users").Doc("w1").Delete(ctx)
+
+	result, err := exe.ExecuteInsert(ctx, NewInsertOperation("users", "w1", map[string]any{
+		"name":   "writer",
+		"friend": NewRef("users/1"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "users/w1", result.Path)
+	assert.False(t, result.UpdateTime.IsZero())
+
+	_, err = exe.ExecuteInsert(ctx, NewInsertOperation("users", "w1", map[string]any{"name": "again"}))
+	assert.Error(t, err)
+
+	_, err = exe.ExecuteSet(ctx, NewSetOperation("users", "w1", map[string]any{"age": int64(30)}, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := exe.ExecuteGet(ctx, NewGetOperation("users", "w1", []string{"name", "age"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]any{"name": "writer", "age": int64(30)}, doc.Data())
+
+	_, err = exe.ExecuteSet(ctx, NewSetOperation("users", "w1", map[string]any{"age": int64(31)}, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = exe.ExecuteGet(ctx, NewGetOperation("users", "w1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]any{"age": int64(31)}, doc.Data())
+
+	result, err = exe.ExecuteInsert(ctx, NewInsertOperation("users/w1/posts", "", map[string]any{"title": "auto"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Regexp(t, `^users/w1/posts/[^/]+$`, result.Path)
+	_, err = fs.Doc(result.Path).Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	ref := fs.Collection("users").Doc("u1")
+	_, err = ref.Set(ctx, map[string]any{
+		"name":    "user",
+		"n":       int64(1),
+		"tags":    []any{"a"},
+		"old":     true,
+		"address": map[string]any{"city": "osaka", "zip": "530"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ref.Delete(ctx)
+
+	_, err = exe.ExecuteUpdate(ctx, NewUpdateOperation("users", "u1", []Update{
+		NewUpdate("address.city", "tokyo"),
+		NewUpdate("n", NewTransform(TRANSFORM_INCREMENT, []any{int64(2)})),
+		NewUpdate("tags", NewTransform(TRANSFORM_ARRAY_UNION, []any{"b"})),
+		NewUpdate("old", NewTransform(TRANSFORM_DELETE_FIELD, nil)),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]any{
+		"name":    "user",
+		"n":       int64(3),
+		"tags":    []any{"a", "b"},
+		"address": map[string]any{"city": "tokyo", "zip": "530"},
+	}, doc.Data())
+
+	_, err = exe.ExecuteUpdate(ctx, NewUpdateOperation("users", "missing", []Update{NewUpdate("name", "x")}))
+	assert.Error(t, err)
+}
+
+func TestPreconditions(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-preconditions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	ref := fs.Collection("users").Doc("u1")
+	defer ref.Delete(ctx)
+
+	set := func(name string, precondition *Precondition) (*WriteResult, error) {
+		op := NewSetOperation("users", "u1", map[string]any{"name": name}, false)
+		op.precondition = precondition
+		return exe.ExecuteSet(ctx, op)
+	}
+
+	_, err = set("a", NewPrecondition(PRECONDITION_EXISTS, time.Time{}))
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	created, err := set("a", NewPrecondition(PRECONDITION_NOT_EXISTS, time.Time{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = set("b", NewPrecondition(PRECONDITION_NOT_EXISTS, time.Time{}))
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	// a write based on the version that was read succeeds once
+	updated, err := set("b", NewPrecondition(PRECONDITION_UPDATED_AT, created.UpdateTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := NewUpdateOperation("users", "u1", []Update{NewUpdate("name", "c")})
+	update.precondition = NewPrecondition(PRECONDITION_UPDATED_AT, created.UpdateTime)
+	_, err = exe.ExecuteUpdate(ctx, update)
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	update.precondition = NewPrecondition(PRECONDITION_UPDATED_AT, updated.UpdateTime)
+	_, err = exe.ExecuteUpdate(ctx, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	del := NewDeleteOperation("users", "u1", false)
+	del.precondition = NewPrecondition(PRECONDITION_UPDATED_AT, updated.UpdateTime)
+	_, err = exe.ExecuteDelete(ctx, del)
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	del.precondition = NewPrecondition(PRECONDITION_EXISTS, time.Time{})
+	_, err = exe.ExecuteDelete(ctx, del)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = exe.ExecuteDelete(ctx, del)
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+}
+
+func TestBulkWrite(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-bulk-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	for i := 1; i <= 5; i++ {
+		_, err := fs.Collection("items").Doc(fmt.Sprint(i)).Set(ctx, map[string]any{"n": int64(i), "status": "active"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for i := 1; i <= 5; i++ {
+			fs.Collection("items").Doc(fmt.Sprint(i)).Delete(ctx)
+		}
+	}()
+
+	var progress []int
+	update := NewBulkUpdateOperation(
+		&QueryOperation{collection: "items", filters: []Filter{NewIntFilter("n", OPERATOR_GT, 2)}},
+		[]Update{NewUpdate("status", "archived")},
+	)
+	result, err := exe.ExecuteBulkUpdate(ctx, update, func(done int, total int) {
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &BulkWriteResult{Matched: 3, Succeeded: 3, Failures: []BulkWriteFailure{}}, result)
+	assert.Equal(t, []int{1, 2, 3}, progress)
+
+	count, err := exe.ExecuteCount(ctx, NewCountOperation("items", []Filter{NewStringFilter("status", OPERATOR_EQ, "archived")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(3), count)
+
+	exe.bulkWriteLimit = 2
+	_, err = exe.ExecuteBulkDelete(ctx, NewBulkDeleteOperation(&QueryOperation{collection: "items"}), nil)
+	assert.ErrorIs(t, err, ErrTooManyDocuments)
+
+	result, err = exe.ExecuteBulkDelete(ctx, NewBulkDeleteOperation(&QueryOperation{collection: "items", filters: []Filter{
+		NewStringFilter("status", OPERATOR_EQ, "archived"),
+	}, limit: 2}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, result.Succeeded)
+
+	count, err = exe.ExecuteCount(ctx, NewCountOperation("items", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(3), count)
+}
+
+func TestRecursiveDelete(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-recursive-delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	for _, path := range []string{
+		"tree/a",
+		"tree/a/posts/1",
+		"tree/a/posts/2",
+		"tree/a/posts/1/comments/1",
+		"tree/b/posts/1", // tree/b exists only as a parent
+	} {
+		if _, err := fs.Doc(path).Set(ctx, map[string]any{"x": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := exe.ExecuteRecursiveDelete(ctx, NewDeleteOperation("tree", "a", true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &RecursiveDeleteResult{
+		Collections: []DeletedCollection{
+			{Path: "tree/a/posts/1/comments", Deleted: 1},
+			{Path: "tree/a/posts", Deleted: 2},
+			{Path: "tree", Deleted: 1},
+		},
+		Failures: []BulkWriteFailure{},
+	}, result)
+
+	result, err = exe.ExecuteDropCollection(ctx, NewDropCollectionOperation("tree"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []DeletedCollection{
+		{Path: "tree/b/posts", Deleted: 1},
+		{Path: "tree", Deleted: 1},
+	}, result.Collections)
+
+	collections, err := findAllCollections(ctx, fs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, collections, "tree")
+
+	// a document is kept when a document below it fails
+	for _, path := range []string{"tree/c", "tree/c/posts/1", "tree/c/posts/2"} {
+		if _, err := fs.Doc(path).Set(ctx, map[string]any{"x": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer exe.ExecuteDropCollection(ctx, NewDropCollectionOperation("tree"), nil)
+	d := exe.newRecursiveDeleter(ctx, nil, false)
+	d.delete = func(bw *firestore.BulkWriter, ref *firestore.DocumentRef) (*firestore.BulkWriterJob, error) {
+		if refPath(ref) == "tree/c/posts/1" {
+			return nil, errors.New("failed")
+		}
+		return bw.Delete(ref)
+	}
+	if err := d.deleteDocument(fs.Doc("tree/c")); err != nil {
+		t.Fatal(err)
+	}
+	result = d.end()
+	assert.Equal(t, []DeletedCollection{{Path: "tree/c/posts", Deleted: 1}}, result.Collections)
+	assert.Equal(t, []BulkWriteFailure{
+		{Path: "tree/c/posts/1", Error: "failed"},
+		{Path: "tree/c", Error: errDescendantNotDeleted.Error()},
+	}, result.Failures)
+
+	doc, err := fs.Doc("tree/c").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, doc.Exists())
+}
+
+func TestCopy(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	createdAt := time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC)
+	data := map[string]any{
+		"n":         int64(1),
+		"createdAt": createdAt,
+		"owner":     fs.Doc("users/a"),
+		"location":  &latlng.LatLng{Latitude: 35.6, Longitude: 139.7},
+		"status":    "active",
+	}
+	for _, path := range []string{"src/a", "src/a/posts/1", "src/c/posts/1"} {
+		if _, err := fs.Doc(path).Set(ctx, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := fs.Doc("src/b").Set(ctx, map[string]any{"status": "archived"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		exe.ExecuteDropCollection(ctx, NewDropCollectionOperation("src"), nil)
+		exe.ExecuteDropCollection(ctx, NewDropCollectionOperation("dst"), nil)
+	}()
+
+	result, err := exe.ExecuteCopy(ctx, NewCopyOperation("src", "a", "dst", "a", true), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &CopyResult{
+		Collections: []CopiedCollection{{Path: "dst", Copied: 1}, {Path: "dst/a/posts", Copied: 1}},
+		Failures:    []BulkWriteFailure{},
+	}, result)
+
+	doc, err := fs.Doc("dst/a/posts/1").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1), doc.Data()["n"])
+	assert.True(t, createdAt.Equal(doc.Data()["createdAt"].(time.Time)))
+	assert.Equal(t, "users/a", refPath(doc.Data()["owner"].(*firestore.DocumentRef)))
+	assert.Equal(t, &latlng.LatLng{Latitude: 35.6, Longitude: 139.7}, doc.Data()["location"])
+
+	// existing documents are not overwritten
+	query := &QueryOperation{collection: "src", filters: []Filter{NewStringFilter("status", OPERATOR_EQ, "active")}}
+	result, err = exe.ExecuteCopy(ctx, NewCopyCollectionOperation(query, "dst", false), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, result.Collections)
+	assert.Len(t, result.Failures, 1)
+
+	// documents that only exist as a parent are traversed
+	result, err = exe.DryRunCopy(ctx, NewCopyCollectionOperation(&QueryOperation{collection: "src"}, "dst2", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.ElementsMatch(t, []CopiedCollection{
+		{Path: "dst2", Copied: 2},
+		{Path: "dst2/a/posts", Copied: 1},
+		{Path: "dst2/c/posts", Copied: 1},
+	}, result.Collections)
+
+	_, err = exe.ExecuteCopy(ctx, NewCopyOperation("src", "missing", "dst", "missing", false), nil)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	// collection copies are limited like bulk writes
+	exe.bulkWriteLimit = 1
+	_, err = exe.DryRunCopy(ctx, NewCopyCollectionOperation(&QueryOperation{collection: "src"}, "dst3", false))
+	assert.ErrorIs(t, err, ErrTooManyDocuments)
+	_, err = exe.DryRunCopy(ctx, NewCopyCollectionOperation(&QueryOperation{collection: "src"}, "dst3", true))
+	assert.ErrorIs(t, err, ErrTooManyDocuments)
+}
+
 func TestCount(t *testing.T) {
 	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
 	ctx := context.Background()
@@ -460,6 +1029,16 @@ func TestCount(t *testing.T) {
 			}),
 			want: 3,
 		},
+		{
+			desc: "count with or",
+			input: NewCountOperation("users", []Filter{
+				NewOrFilter([]Filter{
+					NewIntFilter("age", "==", 20),
+					NewIntFilter("age", "==", 24),
+				}),
+			}),
+			want: 2,
+		},
 		{
 			desc: "count with collection group",
 			input: NewCollectionGroupCountOperation("posts", []Filter{
@@ -480,6 +1059,120 @@ func TestCount(t *testing.T) {
 	}
 }
 
+func TestAggregate(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-aggregate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	err = seed(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanSeed(fs)
+
+	tests := []struct {
+		desc  string
+		input *AggregateOperation
+		want  map[string]any
+	}{
+		{
+			desc: "aggregate",
+			input: NewAggregateOperation("users", []Aggregate{
+				NewAggregate(AGGREGATE_COUNT, "", "COUNT(*)"),
+				NewAggregate(AGGREGATE_SUM, "age", "total"),
+				NewAggregate(AGGREGATE_AVG, "age", "AVG(age)"),
+			}, []Filter{}),
+			want: map[string]any{"COUNT(*)": int64(5), "total": int64(110), "AVG(age)": float64(22)},
+		},
+		{
+			desc: "aggregate with where",
+			input: NewAggregateOperation("users", []Aggregate{
+				NewAggregate(AGGREGATE_SUM, "age", "total"),
+			}, []Filter{
+				NewIntFilter("age", ">=", 23),
+			}),
+			want: map[string]any{"total": int64(47)},
+		},
+		{
+			desc: "aggregate avg without documents",
+			input: NewAggregateOperation("users", []Aggregate{
+				NewAggregate(AGGREGATE_AVG, "age", "avg"),
+			}, []Filter{
+				NewIntFilter("age", ">", 100),
+			}),
+			want: map[string]any{"avg": nil},
+		},
+		{
+			desc: "aggregate with collection group",
+			input: NewCollectionGroupAggregateOperation("posts", []Aggregate{
+				NewAggregate(AGGREGATE_COUNT, "", "count"),
+			}, []Filter{}),
+			want: map[string]any{"count": int64(25)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := exe.ExecuteAggregate(ctx, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-group-by")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+
+	err = seed(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanSeed(fs)
+
+	op := &QueryOperation{
+		collection: "users",
+		aggregates: []Aggregate{
+			NewAggregate(AGGREGATE_COUNT, "", "count"),
+			NewAggregate(AGGREGATE_MAX, "age", "max"),
+		},
+		filters:  []Filter{NewIntFilter("age", ">=", 22)},
+		groupBys: []string{"age"},
+		orderBys: []OrderBy{{"age", firestore.Desc}},
+	}
+
+	t.Run("group by", func(t *testing.T) {
+		got, err := exe.ExecuteGroupBy(ctx, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []map[string]any{
+			{"age": int64(24), "count": int64(1), "max": int64(24)},
+			{"age": int64(23), "count": int64(1), "max": int64(23)},
+			{"age": int64(22), "count": int64(1), "max": int64(22)},
+		}, got)
+	})
+
+	t.Run("group by over scan limit", func(t *testing.T) {
+		exe.groupByScanLimit = 2
+		defer func() { exe.groupByScanLimit = DefaultGroupByScanLimit }()
+
+		_, err := exe.ExecuteGroupBy(ctx, op)
+		assert.ErrorIs(t, err, ErrTooManyDocuments)
+	})
+}
+
 func TestListCollections(t *testing.T) {
 	seed := func(c *firestore.Client) error {
 		ctx := context.Background()
@@ -54,3 +54,12 @@ func normalizeFirestorePath(s string) string {
 	}
 	return s
 }
+
+// refPath returns the path of ref below the database root, such as
+// users/abc.
+func refPath(ref *firestore.DocumentRef) string {
+	if _, path, ok := strings.Cut(ref.Path, "/documents/"); ok {
+		return path
+	}
+	return ref.Path
+}
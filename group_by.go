@@ -0,0 +1,259 @@
+package fscli
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/exp/slices"
+)
+
+// DefaultGroupByScanLimit is the number of documents GROUP BY reads at most
+// before giving up, since grouping happens on the client.
+const DefaultGroupByScanLimit = 10000
+
+// groupByColumns returns the output columns of a GROUP BY query: the
+// selected fields, or all grouped fields without SELECT, followed by the
+// aggregations.
+func groupByColumns(op *QueryOperation) []string {
+	columns := []string{}
+	if len(op.selects) > 0 {
+		columns = append(columns, op.selects...)
+	} else {
+		columns = append(columns, op.groupBys...)
+	}
+	for _, aggregate := range op.aggregates {
+		columns = append(columns, aggregate.alias)
+	}
+	return columns
+}
+
+// groupByFields returns the fields a GROUP BY query needs to read.
+func groupByFields(op *QueryOperation) []string {
+	fields := append([]string{}, op.groupBys...)
+	for _, aggregate := range op.aggregates {
+		if aggregate.field != "" && !slices.Contains(fields, aggregate.field) {
+			fields = append(fields, aggregate.field)
+		}
+	}
+	return fields
+}
+
+type group struct {
+	keys         map[string]any
+	accumulators []*accumulator
+}
+
+// grouper aggregates documents per distinct combination of the GROUP BY
+// field values. Groups keep the order in which they were first seen.
+type grouper struct {
+	op     *QueryOperation
+	paths  map[string]firestore.FieldPath
+	groups map[string]*group
+	order  []string
+}
+
+func newGrouper(op *QueryOperation) (*grouper, error) {
+	paths := map[string]firestore.FieldPath{}
+	for _, field := range groupByFields(op) {
+		if field == FieldDocumentID {
+			continue
+		}
+		fp, err := parseFieldPath(field)
+		if err != nil {
+			return nil, err
+		}
+		paths[field] = fp
+	}
+	return &grouper{op: op, paths: paths, groups: map[string]*group{}}, nil
+}
+
+func (g *grouper) lookup(id string, data map[string]any, field string) (any, bool) {
+	if field == FieldDocumentID {
+		return id, true
+	}
+	return lookupFieldPath(data, g.paths[field])
+}
+
+func (g *grouper) add(id string, data map[string]any) {
+	keys := make(map[string]any, len(g.op.groupBys))
+	values := make([]any, 0, len(g.op.groupBys))
+	for _, field := range g.op.groupBys {
+		v, _ := g.lookup(id, data, field)
+		keys[field] = v
+		values = append(values, v)
+	}
+	key := groupKey(values)
+
+	grp, ok := g.groups[key]
+	if !ok {
+		grp = &group{keys: keys}
+		for _, aggregate := range g.op.aggregates {
+			grp.accumulators = append(grp.accumulators, &accumulator{function: aggregate.function})
+		}
+		g.groups[key] = grp
+		g.order = append(g.order, key)
+	}
+
+	for i, aggregate := range g.op.aggregates {
+		var v any
+		if aggregate.field != "" {
+			v, _ = g.lookup(id, data, aggregate.field)
+		}
+		grp.accumulators[i].add(v)
+	}
+}
+
+func (g *grouper) rows() []map[string]any {
+	rows := make([]map[string]any, 0, len(g.order))
+	for _, key := range g.order {
+		grp := g.groups[key]
+		row := map[string]any{}
+		for _, column := range groupByColumns(g.op) {
+			if v, ok := grp.keys[column]; ok {
+				row[column] = v
+			}
+		}
+		for i, aggregate := range g.op.aggregates {
+			row[aggregate.alias] = grp.accumulators[i].result()
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// groupKey identifies a combination of values. The type is part of the key
+// so that a timestamp and a string with the same text stay apart.
+func groupKey(values []any) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		j, err := json.Marshal(toJSONValue(v))
+		if err != nil {
+			j = []byte(fmt.Sprintf("%#v", v))
+		}
+		parts = append(parts, fmt.Sprintf("%T:%s", v, j))
+	}
+	return strings.Join(parts, ",")
+}
+
+// accumulator computes one aggregation of a group. Like Firestore, SUM and
+// AVG skip non-numeric values, and MIN and MAX skip missing and null ones.
+type accumulator struct {
+	function AggregateFunction
+	count    int64
+	numbers  int64
+	intSum   int64
+	floatSum float64
+	isFloat  bool
+	value    any
+}
+
+func (a *accumulator) add(v any) {
+	a.count++
+
+	switch a.function {
+	case AGGREGATE_SUM, AGGREGATE_AVG:
+		switch n := v.(type) {
+		case int64:
+			a.intSum += n
+			a.numbers++
+		case float64:
+			a.floatSum += n
+			a.isFloat = true
+			a.numbers++
+		}
+	case AGGREGATE_MIN, AGGREGATE_MAX:
+		if v == nil {
+			return
+		}
+		if a.value == nil {
+			a.value = v
+			return
+		}
+		c := compareValues(v, a.value)
+		if (a.function == AGGREGATE_MIN && c < 0) || (a.function == AGGREGATE_MAX && c > 0) {
+			a.value = v
+		}
+	}
+}
+
+func (a *accumulator) result() any {
+	switch a.function {
+	case AGGREGATE_COUNT:
+		return a.count
+	case AGGREGATE_SUM:
+		if a.isFloat {
+			return float64(a.intSum) + a.floatSum
+		}
+		return a.intSum
+	case AGGREGATE_AVG:
+		if a.numbers == 0 {
+			return nil
+		}
+		return (float64(a.intSum) + a.floatSum) / float64(a.numbers)
+	default:
+		return a.value
+	}
+}
+
+// compareValues orders values of different types the way Firestore does:
+// booleans, then numbers, timestamps and strings.
+func compareValues(a any, b any) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return ta - tb
+	}
+
+	switch va := a.(type) {
+	case bool:
+		vb := b.(bool)
+		if va == vb {
+			return 0
+		}
+		if !va {
+			return -1
+		}
+		return 1
+	case int64, float64:
+		if ia, ok := a.(int64); ok {
+			if ib, ok := b.(int64); ok {
+				return cmp.Compare(ia, ib)
+			}
+		}
+		return cmp.Compare(toFloat64(a), toFloat64(b))
+	case time.Time:
+		return va.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(va, b.(string))
+	default:
+		return 0
+	}
+}
+
+func typeOrder(v any) int {
+	switch v.(type) {
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case time.Time:
+		return 3
+	case string:
+		return 4
+	default:
+		return 5
+	}
+}
+
+func toFloat64(v any) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,137 @@
+package fscli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrouper(t *testing.T) {
+	docs := []struct {
+		id   string
+		data map[string]any
+	}{
+		{"1", map[string]any{"status": "paid", "amount": int64(100), "address": map[string]any{"city": "Tokyo"}}},
+		{"2", map[string]any{"status": "open", "amount": 2.5, "address": map[string]any{"city": "Osaka"}}},
+		{"3", map[string]any{"status": "paid", "amount": int64(300), "address": map[string]any{"city": "Tokyo"}}},
+		{"4", map[string]any{"status": "paid", "amount": "n/a", "address": map[string]any{"city": "Osaka"}}},
+		{"5", map[string]any{"amount": int64(50)}},
+	}
+
+	tests := []struct {
+		desc  string
+		input *QueryOperation
+		want  []map[string]any
+	}{
+		{
+			desc: "count and sum",
+			input: &QueryOperation{
+				collection: "orders",
+				selects:    []string{"status"},
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_COUNT, "", "COUNT(*)"),
+					NewAggregate(AGGREGATE_SUM, "amount", "total"),
+				},
+				groupBys: []string{"status"},
+			},
+			want: []map[string]any{
+				{"status": "paid", "COUNT(*)": int64(3), "total": int64(400)},
+				{"status": "open", "COUNT(*)": int64(1), "total": 2.5},
+				{"status": nil, "COUNT(*)": int64(1), "total": int64(50)},
+			},
+		},
+		{
+			desc: "min max and avg",
+			input: &QueryOperation{
+				collection: "orders",
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_MIN, "amount", "min"),
+					NewAggregate(AGGREGATE_MAX, "amount", "max"),
+					NewAggregate(AGGREGATE_AVG, "amount", "avg"),
+				},
+				groupBys: []string{"status"},
+			},
+			want: []map[string]any{
+				{"status": "paid", "min": int64(100), "max": "n/a", "avg": float64(200)},
+				{"status": "open", "min": 2.5, "max": 2.5, "avg": 2.5},
+				{"status": nil, "min": int64(50), "max": int64(50), "avg": float64(50)},
+			},
+		},
+		{
+			desc: "multiple nested fields",
+			input: &QueryOperation{
+				collection: "orders",
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_COUNT, "", "count"),
+				},
+				groupBys: []string{"status", "address.city"},
+			},
+			want: []map[string]any{
+				{"status": "paid", "address.city": "Tokyo", "count": int64(2)},
+				{"status": "open", "address.city": "Osaka", "count": int64(1)},
+				{"status": "paid", "address.city": "Osaka", "count": int64(1)},
+				{"status": nil, "address.city": nil, "count": int64(1)},
+			},
+		},
+		{
+			desc: "document id",
+			input: &QueryOperation{
+				collection: "orders",
+				selects:    []string{"__id__"},
+				groupBys:   []string{"__id__"},
+			},
+			want: []map[string]any{
+				{"__id__": "1"}, {"__id__": "2"}, {"__id__": "3"}, {"__id__": "4"}, {"__id__": "5"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			g, err := newGrouper(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, doc := range docs {
+				g.add(doc.id, doc.data)
+			}
+			assert.Equal(t, tt.want, g.rows())
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		desc string
+		a    any
+		b    any
+		want int
+	}{
+		{"ints", int64(1), int64(2), -1},
+		{"int and float", int64(2), 1.5, 1},
+		{"equal int and float", int64(1), 1.0, 0},
+		{"strings", "b", "a", 1},
+		{"timestamps", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), -1},
+		{"bools", false, true, -1},
+		{"number before string", int64(100), "1", -1},
+		{"bool before number", true, int64(0), -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := compareValues(tt.a, tt.b)
+			assert.Equal(t, tt.want, sign(got))
+		})
+	}
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	if n > 0 {
+		return 1
+	}
+	return 0
+}
@@ -1,5 +1,11 @@
 package fscli
 
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 type Lexer struct {
 	input        []rune
 	position     int
@@ -31,6 +37,24 @@ func (l *Lexer) peekChar() rune {
 	}
 }
 
+// prevChar returns the last character before the current one that is
+// not whitespace.
+func (l *Lexer) prevChar() rune {
+	for i := l.position - 1; i >= 0; i-- {
+		if !unicode.IsSpace(l.input[i]) {
+			return l.input[i]
+		}
+	}
+	return 0
+}
+
+func (l *Lexer) peekCharN(n int) rune {
+	if l.position+n >= len(l.input) {
+		return 0
+	}
+	return l.input[l.position+n]
+}
+
 func (l *Lexer) NextToken() Token {
 	var tok Token
 
@@ -86,6 +110,16 @@ func (l *Lexer) NextToken() Token {
 		tok = newToken(RPAREN, l.ch)
 	case ',':
 		tok = newToken(COMMA, l.ch)
+	case '*':
+		tok = newToken(ASTERISK, l.ch)
+	case '+':
+		tok = newToken(PLUS, l.ch)
+	case '{':
+		tok = newToken(LBRACE, l.ch)
+	case '}':
+		tok = newToken(RBRACE, l.ch)
+	case ':':
+		tok = newToken(COLON, l.ch)
 	case '\\':
 		if isLetter(l.peekChar()) {
 			l.readChar()
@@ -96,7 +130,14 @@ func (l *Lexer) NextToken() Token {
 		tok.Literal = ""
 		tok.Type = EOF
 	default:
-		if isLetter(l.ch) {
+		if l.ch == '-' && (unicode.IsSpace(l.peekChar()) || l.peekChar() == 0 || l.prevChar() == ')') {
+			// a lone - or a - after ) is subtraction, since - may start
+			// an identifier, as in NOW()-INTERVAL '7 days'
+			tok = newToken(MINUS, l.ch)
+		} else if l.ch == '-' && isDigit(l.peekChar()) {
+			tok.Type, tok.Literal = l.readNumber()
+			return tok
+		} else if isLetter(l.ch) || l.ch == '`' {
 			tok.Literal = l.readIdentifier()
 			tok.Type = LookupIdent(tok.Literal)
 			return tok
@@ -116,54 +157,137 @@ func newToken(tokenType TokenType, ch rune) Token {
 	return Token{Type: tokenType, Literal: string(ch)}
 }
 
+// readIdentifier reads an identifier. Backtick-quoted segments such as
+// `display name` may contain any character and are kept with their quotes.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) || isDigit(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '`' {
+		if l.ch == '`' {
+			l.readChar()
+			for l.ch != '`' && l.ch != 0 {
+				if l.ch == '\\' && l.peekChar() != 0 {
+					l.readChar()
+				}
+				l.readChar()
+			}
+			if l.ch == 0 {
+				break
+			}
+		}
 		l.readChar()
 	}
 	return string(l.input[position:l.position])
 }
 
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'"':  '"',
+	'\'': '\'',
+	'`':  '`',
+}
+
+// readString reads a quoted string literal and resolves backslash escapes.
+// Unknown escapes are kept as written.
 func (l *Lexer) readString(quote rune) string {
+	var sb strings.Builder
 	l.readChar()
-	position := l.position
 	for l.ch != quote && l.ch != 0 {
+		if l.ch == '\\' && l.peekChar() != 0 {
+			l.readChar()
+			if r, ok := escapes[l.ch]; ok {
+				sb.WriteRune(r)
+			} else if r, ok := l.readUnicodeEscape(); ok {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteRune('\\')
+				sb.WriteRune(l.ch)
+			}
+		} else {
+			sb.WriteRune(l.ch)
+		}
 		l.readChar()
 	}
+	return sb.String()
+}
 
-	r := string(l.input[position:l.position])
-	return r
+// readUnicodeEscape reads the XXXX part of \uXXXX.
+func (l *Lexer) readUnicodeEscape() (rune, bool) {
+	if l.ch != 'u' || l.position+4 >= len(l.input) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(string(l.input[l.position+1:l.position+5]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	for i := 0; i < 4; i++ {
+		l.readChar()
+	}
+	return rune(n), true
 }
 
+// isLetter reports whether ch can be part of an identifier.
+// Any printable non-ASCII character is accepted since Firestore allows UTF-8 IDs.
 func isLetter(ch rune) bool {
+	if ch > unicode.MaxASCII {
+		return unicode.IsGraphic(ch) && !unicode.IsSpace(ch)
+	}
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '/' || ch == '-' || ch == '.'
 }
 
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for unicode.IsSpace(l.ch) {
 		l.readChar()
 	}
 }
 
+// readNumber reads a signed INT or FLOAT such as -5, 3.14 or 1e9.
+// A number followed by identifier characters (e.g. 2024-logs) is read as IDENT.
 func (l *Lexer) readNumber() (TokenType, string) {
 	position := l.position
-	for isDigit(l.ch) {
+	tokenType := INT
+
+	if l.ch == '-' {
 		l.readChar()
 	}
-
-	if l.ch != '.' {
-		return INT, string(l.input[position:l.position])
+	for isDigit(l.ch) {
+		l.readChar()
 	}
 
 	// float
 	if l.ch == '.' {
+		tokenType = FLOAT
 		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
 	}
-	for isDigit(l.ch) {
-		l.readChar()
+
+	// exponent
+	if l.ch == 'e' || l.ch == 'E' {
+		if isDigit(l.peekChar()) || (l.peekChar() == '-' || l.peekChar() == '+') && isDigit(l.peekCharN(2)) {
+			tokenType = FLOAT
+			l.readChar()
+			if l.ch == '-' || l.ch == '+' {
+				l.readChar()
+			}
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+
+	if isLetter(l.ch) {
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		return IDENT, string(l.input[position:l.position])
 	}
 
-	return FLOAT, string(l.input[position:l.position])
+	return tokenType, string(l.input[position:l.position])
 }
 
 func isDigit(ch rune) bool {
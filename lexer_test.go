@@ -36,6 +36,38 @@ func TestLexer(t *testing.T) {
 				{Type: STRING, Literal: "John Doe"},
 			},
 		},
+		{
+			desc:  "query with escaped string",
+			input: `QUERY user WHERE name = "say \"hi\"\n\t\\ \u00e9 \d" AND nick = 'it\'s'`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "user"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "name"},
+				{Type: EQ, Literal: "="},
+				{Type: STRING, Literal: "say \"hi\"\n\t\\ é \\d"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "nick"},
+				{Type: EQ, Literal: "="},
+				{Type: STRING, Literal: "it's"},
+			},
+		},
+		{
+			desc:  "query with quoted field names",
+			input: "QUERY user SELECT `display name`, `a.b`.c WHERE `select` = 1",
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "user"},
+				{Type: SELECT, Literal: "SELECT"},
+				{Type: IDENT, Literal: "`display name`"},
+				{Type: COMMA, Literal: ","},
+				{Type: IDENT, Literal: "`a.b`.c"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "`select`"},
+				{Type: EQ, Literal: "="},
+				{Type: INT, Literal: "1"},
+			},
+		},
 		{
 			desc:  "query with int",
 			input: `QUERY user WHERE age = 20`,
@@ -60,6 +92,46 @@ func TestLexer(t *testing.T) {
 				{Type: FLOAT, Literal: "20.5"},
 			},
 		},
+		{
+			desc:  "query with signed numbers",
+			input: `QUERY user WHERE age > -5 AND score < -3.5E-2 AND views >= 1e9 AND rate = 2.5e+3`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "user"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "age"},
+				{Type: GT, Literal: ">"},
+				{Type: INT, Literal: "-5"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "score"},
+				{Type: LT, Literal: "<"},
+				{Type: FLOAT, Literal: "-3.5E-2"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "views"},
+				{Type: GTE, Literal: ">="},
+				{Type: FLOAT, Literal: "1e9"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "rate"},
+				{Type: EQ, Literal: "="},
+				{Type: FLOAT, Literal: "2.5e+3"},
+			},
+		},
+		{
+			desc:  "query with hyphenated paths",
+			input: `QUERY 2024-logs/-Mabc/-1x`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "2024-logs/-Mabc/-1x"},
+			},
+		},
+		{
+			desc:  "get with numeric id",
+			input: `GET users/123`,
+			want: []Token{
+				{Type: GET, Literal: "GET"},
+				{Type: IDENT, Literal: "users/123"},
+			},
+		},
 		{
 			desc:  "query subcollection",
 			input: `QUERY users/abc/posts WHERE title = "Hello World"`,
@@ -124,6 +196,28 @@ func TestLexer(t *testing.T) {
 				{Type: INT, Literal: "20"},
 			},
 		},
+		{
+			desc:  "query with or and parentheses",
+			input: `QUERY user WHERE (age = 20 OR age = 30) AND name = "John Doe"`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "user"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: LPAREN, Literal: "("},
+				{Type: IDENT, Literal: "age"},
+				{Type: EQ, Literal: "="},
+				{Type: INT, Literal: "20"},
+				{Type: OR, Literal: "OR"},
+				{Type: IDENT, Literal: "age"},
+				{Type: EQ, Literal: "="},
+				{Type: INT, Literal: "30"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "name"},
+				{Type: EQ, Literal: "="},
+				{Type: STRING, Literal: "John Doe"},
+			},
+		},
 		{
 			desc:  "query with in",
 			input: `QUERY user WHERE name IN ["John Doe", "Jane Doe"]`,
@@ -140,6 +234,54 @@ func TestLexer(t *testing.T) {
 				{Type: RBRACKET, Literal: "]"},
 			},
 		},
+		{
+			desc:  "query with not in",
+			input: `QUERY user WHERE age NOT_IN [20, 21] AND name NOT IN ["a"]`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "user"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "age"},
+				{Type: NOT_IN, Literal: "NOT_IN"},
+				{Type: LBRACKET, Literal: "["},
+				{Type: INT, Literal: "20"},
+				{Type: COMMA, Literal: ","},
+				{Type: INT, Literal: "21"},
+				{Type: RBRACKET, Literal: "]"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "name"},
+				{Type: NOT, Literal: "NOT"},
+				{Type: IN, Literal: "IN"},
+				{Type: LBRACKET, Literal: "["},
+				{Type: STRING, Literal: "a"},
+				{Type: RBRACKET, Literal: "]"},
+			},
+		},
+		{
+			desc:  "query with literals",
+			input: `QUERY user WHERE active = true AND deletedAt IS NOT NULL AND score != NaN AND x = FALSE`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "user"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "active"},
+				{Type: EQ, Literal: "="},
+				{Type: TRUE, Literal: "true"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "deletedAt"},
+				{Type: IS, Literal: "IS"},
+				{Type: NOT, Literal: "NOT"},
+				{Type: NULL, Literal: "NULL"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "score"},
+				{Type: NOT_EQ, Literal: "!="},
+				{Type: NAN, Literal: "NaN"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "x"},
+				{Type: EQ, Literal: "="},
+				{Type: FALSE, Literal: "FALSE"},
+			},
+		},
 		{
 			desc:  "query with GT",
 			input: `QUERY user WHERE age > 20`,
@@ -259,6 +401,30 @@ func TestLexer(t *testing.T) {
 				{Type: STRING, Literal: "👍"},
 			},
 		},
+		{
+			desc:  "query with unicode identifiers",
+			input: "QUERY ユーザー/山田/投稿\u3000WHERE 名前 = \"太郎\" AND café = 1",
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "ユーザー/山田/投稿"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "名前"},
+				{Type: EQ, Literal: "="},
+				{Type: STRING, Literal: "太郎"},
+				{Type: AND, Literal: "AND"},
+				{Type: IDENT, Literal: "café"},
+				{Type: EQ, Literal: "="},
+				{Type: INT, Literal: "1"},
+			},
+		},
+		{
+			desc:  "get with emoji id",
+			input: `GET users/👍１`,
+			want: []Token{
+				{Type: GET, Literal: "GET"},
+				{Type: IDENT, Literal: "users/👍１"},
+			},
+		},
 		{
 			desc:  "query with select",
 			input: `QUERY users SELECT name, age`,
@@ -357,6 +523,147 @@ func TestLexer(t *testing.T) {
 				{Type: INT, Literal: "10"},
 			},
 		},
+		{
+			desc:  "query with cursors",
+			input: `QUERY users ORDER BY age START AFTER 20 END BEFORE 30`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "users"},
+				{Type: ORDER, Literal: "ORDER"},
+				{Type: BY, Literal: "BY"},
+				{Type: IDENT, Literal: "age"},
+				{Type: START, Literal: "START"},
+				{Type: AFTER, Literal: "AFTER"},
+				{Type: INT, Literal: "20"},
+				{Type: END, Literal: "END"},
+				{Type: BEFORE, Literal: "BEFORE"},
+				{Type: INT, Literal: "30"},
+			},
+		},
+		{
+			desc:  "query with limit last and offset",
+			input: `QUERY users ORDER BY age LIMIT LAST 5 OFFSET 10`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "users"},
+				{Type: ORDER, Literal: "ORDER"},
+				{Type: BY, Literal: "BY"},
+				{Type: IDENT, Literal: "age"},
+				{Type: LIMIT, Literal: "LIMIT"},
+				{Type: LAST, Literal: "LAST"},
+				{Type: INT, Literal: "5"},
+				{Type: OFFSET, Literal: "OFFSET"},
+				{Type: INT, Literal: "10"},
+			},
+		},
+		{
+			desc:  "aggregate",
+			input: `AGGREGATE orders COUNT(*), SUM(amount) AS total`,
+			want: []Token{
+				{Type: AGGREGATE, Literal: "AGGREGATE"},
+				{Type: IDENT, Literal: "orders"},
+				{Type: COUNT, Literal: "COUNT"},
+				{Type: LPAREN, Literal: "("},
+				{Type: ASTERISK, Literal: "*"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: COMMA, Literal: ","},
+				{Type: IDENT, Literal: "SUM"},
+				{Type: LPAREN, Literal: "("},
+				{Type: IDENT, Literal: "amount"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: AS, Literal: "AS"},
+				{Type: IDENT, Literal: "total"},
+			},
+		},
+		{
+			desc:  "query with group by",
+			input: `QUERY orders SELECT status, MAX(amount) GROUP BY status`,
+			want: []Token{
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "orders"},
+				{Type: SELECT, Literal: "SELECT"},
+				{Type: IDENT, Literal: "status"},
+				{Type: COMMA, Literal: ","},
+				{Type: IDENT, Literal: "MAX"},
+				{Type: LPAREN, Literal: "("},
+				{Type: IDENT, Literal: "amount"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: GROUP, Literal: "GROUP"},
+				{Type: BY, Literal: "BY"},
+				{Type: IDENT, Literal: "status"},
+			},
+		},
+		{
+			desc:  "find nearest",
+			input: `FIND NEAREST embedding TO VECTOR([0.5, -1]) DISTANCE EUCLIDEAN LIMIT 3 DISTANCE FIELD d`,
+			want: []Token{
+				{Type: IDENT, Literal: "FIND"},
+				{Type: IDENT, Literal: "NEAREST"},
+				{Type: IDENT, Literal: "embedding"},
+				{Type: IDENT, Literal: "TO"},
+				{Type: IDENT, Literal: "VECTOR"},
+				{Type: LPAREN, Literal: "("},
+				{Type: LBRACKET, Literal: "["},
+				{Type: FLOAT, Literal: "0.5"},
+				{Type: COMMA, Literal: ","},
+				{Type: INT, Literal: "-1"},
+				{Type: RBRACKET, Literal: "]"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: IDENT, Literal: "DISTANCE"},
+				{Type: IDENT, Literal: "EUCLIDEAN"},
+				{Type: LIMIT, Literal: "LIMIT"},
+				{Type: INT, Literal: "3"},
+				{Type: IDENT, Literal: "DISTANCE"},
+				{Type: IDENT, Literal: "FIELD"},
+				{Type: IDENT, Literal: "d"},
+			},
+		},
+		{
+			desc:  "interval arithmetic",
+			input: `NOW() - INTERVAL '7 days' + INTERVAL '1 hour'`,
+			want: []Token{
+				{Type: IDENT, Literal: "NOW"},
+				{Type: LPAREN, Literal: "("},
+				{Type: RPAREN, Literal: ")"},
+				{Type: MINUS, Literal: "-"},
+				{Type: INTERVAL, Literal: "INTERVAL"},
+				{Type: STRING, Literal: "7 days"},
+				{Type: PLUS, Literal: "+"},
+				{Type: INTERVAL, Literal: "INTERVAL"},
+				{Type: STRING, Literal: "1 hour"},
+			},
+		},
+		{
+			desc:  "interval arithmetic without spaces",
+			input: `TIMESTAMP("2024-01-02")-INTERVAL '1 day'`,
+			want: []Token{
+				{Type: IDENT, Literal: "TIMESTAMP"},
+				{Type: LPAREN, Literal: "("},
+				{Type: STRING, Literal: "2024-01-02"},
+				{Type: RPAREN, Literal: ")"},
+				{Type: MINUS, Literal: "-"},
+				{Type: INTERVAL, Literal: "INTERVAL"},
+				{Type: STRING, Literal: "1 day"},
+			},
+		},
+		{
+			desc:  "timezone",
+			input: `\timezone America/Port-au-Prince`,
+			want: []Token{
+				{Type: SET_TIMEZONE, Literal: `\timezone`},
+				{Type: IDENT, Literal: "America/Port-au-Prince"},
+			},
+		},
+		{
+			desc:  "explain analyze",
+			input: `EXPLAIN ANALYZE QUERY users`,
+			want: []Token{
+				{Type: EXPLAIN, Literal: "EXPLAIN"},
+				{Type: ANALYZE, Literal: "ANALYZE"},
+				{Type: QUERY, Literal: "QUERY"},
+				{Type: IDENT, Literal: "users"},
+			},
+		},
 		{
 			desc:  "count",
 			input: `COUNT users WHERE name = "John Doe"`,
@@ -369,6 +676,64 @@ func TestLexer(t *testing.T) {
 				{Type: STRING, Literal: "John Doe"},
 			},
 		},
+		{
+			desc:  "insert with object",
+			input: `INSERT INTO users ID "abc" VALUES {name: "John", tags: ["a"]}`,
+			want: []Token{
+				{Type: INSERT, Literal: "INSERT"},
+				{Type: INTO, Literal: "INTO"},
+				{Type: IDENT, Literal: "users"},
+				{Type: IDENT, Literal: "ID"},
+				{Type: STRING, Literal: "abc"},
+				{Type: VALUES, Literal: "VALUES"},
+				{Type: LBRACE, Literal: "{"},
+				{Type: IDENT, Literal: "name"},
+				{Type: COLON, Literal: ":"},
+				{Type: STRING, Literal: "John"},
+				{Type: COMMA, Literal: ","},
+				{Type: IDENT, Literal: "tags"},
+				{Type: COLON, Literal: ":"},
+				{Type: LBRACKET, Literal: "["},
+				{Type: STRING, Literal: "a"},
+				{Type: RBRACKET, Literal: "]"},
+				{Type: RBRACE, Literal: "}"},
+			},
+		},
+		{
+			desc:  "set merge",
+			input: `SET users/abc {} MERGE`,
+			want: []Token{
+				{Type: SET, Literal: "SET"},
+				{Type: IDENT, Literal: "users/abc"},
+				{Type: LBRACE, Literal: "{"},
+				{Type: RBRACE, Literal: "}"},
+				{Type: MERGE, Literal: "MERGE"},
+			},
+		},
+		{
+			desc:  "delete from",
+			input: `DELETE FROM users WHERE old = DELETE_FIELD()`,
+			want: []Token{
+				{Type: DELETE, Literal: "DELETE"},
+				{Type: FROM, Literal: "FROM"},
+				{Type: IDENT, Literal: "users"},
+				{Type: WHERE, Literal: "WHERE"},
+				{Type: IDENT, Literal: "old"},
+				{Type: EQ, Literal: "="},
+				{Type: IDENT, Literal: "DELETE_FIELD"},
+				{Type: LPAREN, Literal: "("},
+				{Type: RPAREN, Literal: ")"},
+			},
+		},
+		{
+			desc:  "drop collection",
+			input: `DROP COLLECTION users/abc/posts`,
+			want: []Token{
+				{Type: DROP, Literal: "DROP"},
+				{Type: COLLECTION, Literal: "COLLECTION"},
+				{Type: IDENT, Literal: "users/abc/posts"},
+			},
+		},
 		{
 			desc:  "list collections",
 			input: `\d`,
@@ -384,6 +749,29 @@ func TestLexer(t *testing.T) {
 				{Type: IDENT, Literal: "on"},
 			},
 		},
+		{
+			desc:  "dry run off",
+			input: `\dryrun off`,
+			want: []Token{
+				{Type: SET_DRY_RUN, Literal: `\dryrun`},
+				{Type: IDENT, Literal: "off"},
+			},
+		},
+		{
+			desc:  "delete if updated at",
+			input: `DELETE users/abc if updated_at = TIMESTAMP("2024-01-02")`,
+			want: []Token{
+				{Type: DELETE, Literal: "DELETE"},
+				{Type: IDENT, Literal: "users/abc"},
+				{Type: IF, Literal: "if"},
+				{Type: IDENT, Literal: "updated_at"},
+				{Type: EQ, Literal: "="},
+				{Type: IDENT, Literal: "TIMESTAMP"},
+				{Type: LPAREN, Literal: "("},
+				{Type: STRING, Literal: "2024-01-02"},
+				{Type: RPAREN, Literal: ")"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,5 +1,7 @@
 package fscli
 
+import "time"
+
 type Metacommand interface {
 	Type() string
 	MetacommandType() string
@@ -29,3 +31,36 @@ type MetacommandPager struct {
 func (m *MetacommandPager) MetacommandType() string {
 	return "Pager"
 }
+
+// MetacommandAsOf sets the read time used by later operations.
+// A zero asOf reads the latest data again.
+type MetacommandAsOf struct {
+	BaseMetacommand
+	asOf time.Time
+}
+
+func (m *MetacommandAsOf) MetacommandType() string {
+	return "AsOf"
+}
+
+// MetacommandTimezone sets the session time zone. A nil location shows
+// the current one.
+type MetacommandTimezone struct {
+	BaseMetacommand
+	location *time.Location
+}
+
+func (m *MetacommandTimezone) MetacommandType() string {
+	return "Timezone"
+}
+
+// MetacommandDryRun turns dry-run mode on or off. In dry-run mode writes
+// show what they would change instead of writing.
+type MetacommandDryRun struct {
+	BaseMetacommand
+	on bool
+}
+
+func (m *MetacommandDryRun) MetacommandType() string {
+	return "DryRun"
+}
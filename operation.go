@@ -1,17 +1,31 @@
 package fscli
 
 import (
+	"fmt"
+	"math"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 type OperationType string
 
 const (
-	OPERATION_TYPE_QUERY OperationType = "QUERY"
-	OPERATION_TYPE_GET   OperationType = "GET"
-	OPERATION_TYPE_COUNT OperationType = "COUNT"
+	OPERATION_TYPE_QUERY     OperationType = "QUERY"
+	OPERATION_TYPE_GET       OperationType = "GET"
+	OPERATION_TYPE_COUNT     OperationType = "COUNT"
+	OPERATION_TYPE_AGGREGATE OperationType = "AGGREGATE"
+	OPERATION_TYPE_EXPLAIN   OperationType = "EXPLAIN"
+	OPERATION_TYPE_INSERT    OperationType = "INSERT"
+	OPERATION_TYPE_SET       OperationType = "SET"
+	OPERATION_TYPE_UPDATE    OperationType = "UPDATE"
+	OPERATION_TYPE_DELETE    OperationType = "DELETE"
+	OPERATION_TYPE_DROP      OperationType = "DROP"
+	OPERATION_TYPE_COPY      OperationType = "COPY"
+	OPERATION_TYPE_BEGIN     OperationType = "BEGIN"
+	OPERATION_TYPE_COMMIT    OperationType = "COMMIT"
+	OPERATION_TYPE_ROLLBACK  OperationType = "ROLLBACK"
 )
 
 type Operation interface {
@@ -20,6 +34,16 @@ type Operation interface {
 	Collection() string
 }
 
+// isWriteOperation reports whether op changes data.
+func isWriteOperation(op Operation) bool {
+	switch op.OperationType() {
+	case OPERATION_TYPE_INSERT, OPERATION_TYPE_SET, OPERATION_TYPE_UPDATE, OPERATION_TYPE_DELETE, OPERATION_TYPE_DROP, OPERATION_TYPE_COPY:
+		return true
+	default:
+		return false
+	}
+}
+
 type BaseOperation struct {
 }
 
@@ -39,8 +63,12 @@ const (
 	OPERATOR_LT                 Operator = "<"
 	OPERATOR_LTE                Operator = "<="
 	OPERATOR_IN                 Operator = "in"
+	OPERATOR_NOT_IN             Operator = "not-in"
 	OPERATOR_ARRAY_CONTAINS     Operator = "array-contains"
 	OPERATOR_ARRAY_CONTAINS_ANY Operator = "array-contains-any"
+
+	OPERATOR_AND Operator = "AND"
+	OPERATOR_OR  Operator = "OR"
 )
 
 type Filter interface {
@@ -63,10 +91,10 @@ func (f *BaseFilter) Operator() Operator {
 
 type IntFilter struct {
 	BaseFilter
-	value int
+	value int64
 }
 
-func NewIntFilter(field string, operator Operator, value int) *IntFilter {
+func NewIntFilter(field string, operator Operator, value int64) *IntFilter {
 	return &IntFilter{BaseFilter{field, operator}, value}
 }
 
@@ -100,6 +128,43 @@ func (f *StringFilter) Value() any {
 	return f.value
 }
 
+type BoolFilter struct {
+	BaseFilter
+	value bool
+}
+
+func NewBoolFilter(field string, operator Operator, value bool) *BoolFilter {
+	return &BoolFilter{BaseFilter{field, operator}, value}
+}
+
+func (f *BoolFilter) Value() any {
+	return f.value
+}
+
+type NullFilter struct {
+	BaseFilter
+}
+
+func NewNullFilter(field string, operator Operator) *NullFilter {
+	return &NullFilter{BaseFilter{field, operator}}
+}
+
+func (f *NullFilter) Value() any {
+	return nil
+}
+
+type NaNFilter struct {
+	BaseFilter
+}
+
+func NewNaNFilter(field string, operator Operator) *NaNFilter {
+	return &NaNFilter{BaseFilter{field, operator}}
+}
+
+func (f *NaNFilter) Value() any {
+	return math.NaN()
+}
+
 type ArrayFilter struct {
 	BaseFilter
 	value []any
@@ -126,19 +191,156 @@ func (f *TimestampFilter) Value() any {
 	return f.value
 }
 
+// Ref is a REF("path") value. The executor resolves it to a document
+// reference, since the parser has no client.
+type Ref struct {
+	path string
+}
+
+func NewRef(path string) Ref {
+	return Ref{path: path}
+}
+
+func (r Ref) Path() string {
+	return r.path
+}
+
+type RefFilter struct {
+	BaseFilter
+	value Ref
+}
+
+func NewRefFilter(field string, operator Operator, value Ref) *RefFilter {
+	return &RefFilter{BaseFilter{field, operator}, value}
+}
+
+func (f *RefFilter) Value() any {
+	return f.value
+}
+
+type GeoPointFilter struct {
+	BaseFilter
+	value *latlng.LatLng
+}
+
+func NewGeoPointFilter(field string, operator Operator, value *latlng.LatLng) *GeoPointFilter {
+	return &GeoPointFilter{BaseFilter{field, operator}, value}
+}
+
+func (f *GeoPointFilter) Value() any {
+	return f.value
+}
+
+type BytesFilter struct {
+	BaseFilter
+	value []byte
+}
+
+func NewBytesFilter(field string, operator Operator, value []byte) *BytesFilter {
+	return &BytesFilter{BaseFilter{field, operator}, value}
+}
+
+func (f *BytesFilter) Value() any {
+	return f.value
+}
+
+type VectorFilter struct {
+	BaseFilter
+	value firestore.Vector64
+}
+
+func NewVectorFilter(field string, operator Operator, value firestore.Vector64) *VectorFilter {
+	return &VectorFilter{BaseFilter{field, operator}, value}
+}
+
+func (f *VectorFilter) Value() any {
+	return f.value
+}
+
+// CompositeFilter combines child filters with AND or OR.
+// FieldName is empty and Value returns the child filters.
+type CompositeFilter struct {
+	operator Operator
+	filters  []Filter
+}
+
+func NewAndFilter(filters []Filter) *CompositeFilter {
+	return &CompositeFilter{OPERATOR_AND, filters}
+}
+
+func NewOrFilter(filters []Filter) *CompositeFilter {
+	return &CompositeFilter{OPERATOR_OR, filters}
+}
+
+func (f *CompositeFilter) FieldName() string {
+	return ""
+}
+
+func (f *CompositeFilter) Operator() Operator {
+	return f.operator
+}
+
+func (f *CompositeFilter) Value() any {
+	return f.filters
+}
+
+func (f *CompositeFilter) Filters() []Filter {
+	return f.filters
+}
+
 type OrderBy struct {
 	field     string
 	direction firestore.Direction
 }
 
+// Cursor is a query cursor given either as values matching the ORDER BY
+// fields or as a document path. An inclusive start cursor is START AT and
+// an inclusive end cursor is END AT.
+type Cursor struct {
+	inclusive bool
+	values    []any
+	docPath   string
+}
+
+func NewValuesCursor(inclusive bool, values []any) *Cursor {
+	return &Cursor{inclusive: inclusive, values: values}
+}
+
+func NewDocumentCursor(inclusive bool, docPath string) *Cursor {
+	return &Cursor{inclusive: inclusive, docPath: docPath}
+}
+
+// FindNearest is a vector similarity search returning the limit documents
+// closest to vector. The distance of each result is written to
+// distanceField when it is set.
+type FindNearest struct {
+	field         string
+	vector        firestore.Vector64
+	measure       firestore.DistanceMeasure
+	limit         int
+	distanceField string
+}
+
+func NewFindNearest(field string, vector firestore.Vector64, measure firestore.DistanceMeasure, limit int, distanceField string) *FindNearest {
+	return &FindNearest{field: field, vector: vector, measure: measure, limit: limit, distanceField: distanceField}
+}
+
 type QueryOperation struct {
 	BaseOperation
 	collection      string
 	collectionGroup bool
 	selects         []string
+	aggregates      []Aggregate
 	filters         []Filter
+	groupBys        []string
 	orderBys        []OrderBy
+	startCursor     *Cursor
+	endCursor       *Cursor
 	limit           int
+	limitToLast     bool
+	offset          int
+	findNearest     *FindNearest
+	asOf            time.Time
 }
 
 func NewQueryOperation(collection string, selects []string, filters []Filter, orderBys []OrderBy, limit int) *QueryOperation {
@@ -161,11 +363,20 @@ func (op *QueryOperation) IsCollectionGroup() bool {
 	return op.collectionGroup
 }
 
+func (op *QueryOperation) AsOf() time.Time {
+	return op.asOf
+}
+
+func (op *QueryOperation) IsGroupBy() bool {
+	return len(op.groupBys) > 0
+}
+
 type GetOperation struct {
 	BaseOperation
 	collection string
 	docId      string
 	selects    []string
+	asOf       time.Time
 }
 
 func NewGetOperation(collection string, docId string, selects []string) *GetOperation {
@@ -188,11 +399,16 @@ func (op *GetOperation) Selects() []string {
 	return op.selects
 }
 
+func (op *GetOperation) AsOf() time.Time {
+	return op.asOf
+}
+
 type CountOperation struct {
 	BaseOperation
 	collection      string
 	collectionGroup bool
 	filters         []Filter
+	asOf            time.Time
 }
 
 func NewCountOperation(collection string, filters []Filter) *CountOperation {
@@ -214,3 +430,448 @@ func (op *CountOperation) Collection() string {
 func (op *CountOperation) IsCollectionGroup() bool {
 	return op.collectionGroup
 }
+
+func (op *CountOperation) AsOf() time.Time {
+	return op.asOf
+}
+
+type AggregateFunction string
+
+const (
+	AGGREGATE_COUNT AggregateFunction = "COUNT"
+	AGGREGATE_SUM   AggregateFunction = "SUM"
+	AGGREGATE_AVG   AggregateFunction = "AVG"
+	AGGREGATE_MIN   AggregateFunction = "MIN"
+	AGGREGATE_MAX   AggregateFunction = "MAX"
+)
+
+// Aggregate is an aggregation such as SUM(amount) AS total. The field is
+// empty for COUNT(*).
+type Aggregate struct {
+	function AggregateFunction
+	field    string
+	alias    string
+}
+
+func NewAggregate(function AggregateFunction, field string, alias string) Aggregate {
+	return Aggregate{function: function, field: field, alias: alias}
+}
+
+func (a Aggregate) Function() AggregateFunction {
+	return a.function
+}
+
+func (a Aggregate) FieldName() string {
+	return a.field
+}
+
+func (a Aggregate) Alias() string {
+	return a.alias
+}
+
+type AggregateOperation struct {
+	BaseOperation
+	collection      string
+	collectionGroup bool
+	aggregates      []Aggregate
+	filters         []Filter
+}
+
+func NewAggregateOperation(collection string, aggregates []Aggregate, filters []Filter) *AggregateOperation {
+	return &AggregateOperation{collection: collection, aggregates: aggregates, filters: filters}
+}
+
+func NewCollectionGroupAggregateOperation(collectionGroup string, aggregates []Aggregate, filters []Filter) *AggregateOperation {
+	return &AggregateOperation{collection: collectionGroup, collectionGroup: true, aggregates: aggregates, filters: filters}
+}
+
+func (op *AggregateOperation) OperationType() OperationType {
+	return OPERATION_TYPE_AGGREGATE
+}
+
+func (op *AggregateOperation) Collection() string {
+	return op.collection
+}
+
+func (op *AggregateOperation) IsCollectionGroup() bool {
+	return op.collectionGroup
+}
+
+func (op *AggregateOperation) Aggregates() []Aggregate {
+	return op.aggregates
+}
+
+// ExplainOperation explains the plan of a query. With analyze the query is
+// also run to collect execution statistics.
+type ExplainOperation struct {
+	BaseOperation
+	analyze bool
+	query   *QueryOperation
+}
+
+func NewExplainOperation(analyze bool, query *QueryOperation) *ExplainOperation {
+	return &ExplainOperation{analyze: analyze, query: query}
+}
+
+func (op *ExplainOperation) OperationType() OperationType {
+	return OPERATION_TYPE_EXPLAIN
+}
+
+func (op *ExplainOperation) Collection() string {
+	return op.query.Collection()
+}
+
+func (op *ExplainOperation) IsAnalyze() bool {
+	return op.analyze
+}
+
+func (op *ExplainOperation) Query() *QueryOperation {
+	return op.query
+}
+
+// InsertOperation creates a document. Without docId Firestore picks
+// the ID.
+type InsertOperation struct {
+	BaseOperation
+	collection string
+	docId      string
+	data       map[string]any
+}
+
+func NewInsertOperation(collection string, docId string, data map[string]any) *InsertOperation {
+	return &InsertOperation{collection: collection, docId: docId, data: data}
+}
+
+func (op *InsertOperation) OperationType() OperationType {
+	return OPERATION_TYPE_INSERT
+}
+
+func (op *InsertOperation) Collection() string {
+	return op.collection
+}
+
+func (op *InsertOperation) DocId() string {
+	return op.docId
+}
+
+func (op *InsertOperation) Data() map[string]any {
+	return op.data
+}
+
+// SetOperation overwrites a document, or with merge only the given fields.
+type SetOperation struct {
+	BaseOperation
+	collection   string
+	docId        string
+	data         map[string]any
+	merge        bool
+	precondition *Precondition
+}
+
+func NewSetOperation(collection string, docId string, data map[string]any, merge bool) *SetOperation {
+	return &SetOperation{collection: collection, docId: docId, data: data, merge: merge}
+}
+
+func (op *SetOperation) OperationType() OperationType {
+	return OPERATION_TYPE_SET
+}
+
+func (op *SetOperation) Collection() string {
+	return op.collection
+}
+
+func (op *SetOperation) DocId() string {
+	return op.docId
+}
+
+func (op *SetOperation) Data() map[string]any {
+	return op.data
+}
+
+func (op *SetOperation) IsMerge() bool {
+	return op.merge
+}
+
+func (op *SetOperation) Precondition() *Precondition {
+	return op.precondition
+}
+
+type PreconditionType string
+
+const (
+	PRECONDITION_EXISTS     PreconditionType = "EXISTS"
+	PRECONDITION_NOT_EXISTS PreconditionType = "NOT EXISTS"
+	PRECONDITION_UPDATED_AT PreconditionType = "UPDATED_AT"
+)
+
+// Precondition is the IF clause of a write. The write fails unless the
+// document exists, does not exist, or was last updated at updateTime.
+type Precondition struct {
+	preconditionType PreconditionType
+	updateTime       time.Time
+}
+
+func NewPrecondition(preconditionType PreconditionType, updateTime time.Time) *Precondition {
+	return &Precondition{preconditionType: preconditionType, updateTime: updateTime}
+}
+
+func (p *Precondition) PreconditionType() PreconditionType {
+	return p.preconditionType
+}
+
+func (p *Precondition) UpdateTime() time.Time {
+	return p.updateTime
+}
+
+// holds reports whether a document that exists or not, last updated at
+// updateTime, satisfies p.
+func (p *Precondition) holds(exists bool, updateTime time.Time) bool {
+	switch p.preconditionType {
+	case PRECONDITION_EXISTS:
+		return exists
+	case PRECONDITION_NOT_EXISTS:
+		return !exists
+	case PRECONDITION_UPDATED_AT:
+		return exists && updateTime.Equal(p.updateTime)
+	}
+	return false
+}
+
+// String returns p as written in a statement.
+func (p *Precondition) String() string {
+	if p.preconditionType == PRECONDITION_UPDATED_AT {
+		return fmt.Sprintf("IF UPDATED_AT = TIMESTAMP(%q)", p.updateTime.Format(time.RFC3339Nano))
+	}
+	return "IF " + string(p.preconditionType)
+}
+
+type TransformType string
+
+const (
+	TRANSFORM_INCREMENT        TransformType = "INCREMENT"
+	TRANSFORM_ARRAY_UNION      TransformType = "ARRAY_UNION"
+	TRANSFORM_ARRAY_REMOVE     TransformType = "ARRAY_REMOVE"
+	TRANSFORM_DELETE_FIELD     TransformType = "DELETE_FIELD"
+	TRANSFORM_SERVER_TIMESTAMP TransformType = "SERVER_TIMESTAMP"
+)
+
+// Transform is a server-side change of a field such as INCREMENT(1). The
+// values are the arguments.
+type Transform struct {
+	transformType TransformType
+	values        []any
+}
+
+func NewTransform(transformType TransformType, values []any) Transform {
+	return Transform{transformType: transformType, values: values}
+}
+
+func (t Transform) TransformType() TransformType {
+	return t.transformType
+}
+
+func (t Transform) Values() []any {
+	return t.values
+}
+
+// Update sets a field to a value or applies a Transform to it.
+type Update struct {
+	field string
+	value any
+}
+
+func NewUpdate(field string, value any) Update {
+	return Update{field: field, value: value}
+}
+
+func (u Update) FieldName() string {
+	return u.field
+}
+
+func (u Update) Value() any {
+	return u.value
+}
+
+// UpdateOperation changes fields of an existing document, or with query
+// of every document it matches.
+type UpdateOperation struct {
+	BaseOperation
+	collection   string
+	docId        string
+	updates      []Update
+	query        *QueryOperation
+	precondition *Precondition
+}
+
+func NewUpdateOperation(collection string, docId string, updates []Update) *UpdateOperation {
+	return &UpdateOperation{collection: collection, docId: docId, updates: updates}
+}
+
+func NewBulkUpdateOperation(query *QueryOperation, updates []Update) *UpdateOperation {
+	return &UpdateOperation{collection: query.Collection(), updates: updates, query: query}
+}
+
+func (op *UpdateOperation) OperationType() OperationType {
+	return OPERATION_TYPE_UPDATE
+}
+
+func (op *UpdateOperation) Collection() string {
+	return op.collection
+}
+
+func (op *UpdateOperation) DocId() string {
+	return op.docId
+}
+
+func (op *UpdateOperation) Updates() []Update {
+	return op.updates
+}
+
+func (op *UpdateOperation) Query() *QueryOperation {
+	return op.query
+}
+
+func (op *UpdateOperation) IsBulk() bool {
+	return op.query != nil
+}
+
+func (op *UpdateOperation) Precondition() *Precondition {
+	return op.precondition
+}
+
+// DeleteOperation deletes a document, with recursive also its
+// subcollections, or with query every document it matches.
+type DeleteOperation struct {
+	BaseOperation
+	collection   string
+	docId        string
+	recursive    bool
+	query        *QueryOperation
+	precondition *Precondition
+}
+
+func NewDeleteOperation(collection string, docId string, recursive bool) *DeleteOperation {
+	return &DeleteOperation{collection: collection, docId: docId, recursive: recursive}
+}
+
+func NewBulkDeleteOperation(query *QueryOperation) *DeleteOperation {
+	return &DeleteOperation{collection: query.Collection(), query: query}
+}
+
+func (op *DeleteOperation) OperationType() OperationType {
+	return OPERATION_TYPE_DELETE
+}
+
+func (op *DeleteOperation) Collection() string {
+	return op.collection
+}
+
+func (op *DeleteOperation) DocId() string {
+	return op.docId
+}
+
+func (op *DeleteOperation) IsRecursive() bool {
+	return op.recursive
+}
+
+func (op *DeleteOperation) Query() *QueryOperation {
+	return op.query
+}
+
+func (op *DeleteOperation) IsBulk() bool {
+	return op.query != nil
+}
+
+func (op *DeleteOperation) Precondition() *Precondition {
+	return op.precondition
+}
+
+// DropCollectionOperation deletes every document of a collection and
+// their subcollections.
+type DropCollectionOperation struct {
+	BaseOperation
+	collection string
+}
+
+func NewDropCollectionOperation(collection string) *DropCollectionOperation {
+	return &DropCollectionOperation{collection: collection}
+}
+
+func (op *DropCollectionOperation) OperationType() OperationType {
+	return OPERATION_TYPE_DROP
+}
+
+func (op *DropCollectionOperation) Collection() string {
+	return op.collection
+}
+
+// CopyOperation copies a document to another path, or with query the
+// documents of a collection it matches into another collection. With
+// recursive their subcollections are copied too.
+type CopyOperation struct {
+	BaseOperation
+	collection   string
+	docId        string
+	toCollection string
+	toDocId      string
+	query        *QueryOperation
+	recursive    bool
+}
+
+func NewCopyOperation(collection string, docId string, toCollection string, toDocId string, recursive bool) *CopyOperation {
+	return &CopyOperation{collection: collection, docId: docId, toCollection: toCollection, toDocId: toDocId, recursive: recursive}
+}
+
+func NewCopyCollectionOperation(query *QueryOperation, toCollection string, recursive bool) *CopyOperation {
+	return &CopyOperation{collection: query.Collection(), toCollection: toCollection, query: query, recursive: recursive}
+}
+
+func (op *CopyOperation) OperationType() OperationType {
+	return OPERATION_TYPE_COPY
+}
+
+func (op *CopyOperation) Collection() string {
+	return op.collection
+}
+
+func (op *CopyOperation) DocId() string {
+	return op.docId
+}
+
+func (op *CopyOperation) ToCollection() string {
+	return op.toCollection
+}
+
+func (op *CopyOperation) ToDocId() string {
+	return op.toDocId
+}
+
+func (op *CopyOperation) Query() *QueryOperation {
+	return op.query
+}
+
+func (op *CopyOperation) IsCollection() bool {
+	return op.query != nil
+}
+
+func (op *CopyOperation) IsRecursive() bool {
+	return op.recursive
+}
+
+// TransactionOperation starts, commits or rolls back a transaction.
+type TransactionOperation struct {
+	BaseOperation
+	operationType OperationType
+}
+
+func NewTransactionOperation(operationType OperationType) *TransactionOperation {
+	return &TransactionOperation{operationType: operationType}
+}
+
+func (op *TransactionOperation) OperationType() OperationType {
+	return op.operationType
+}
+
+func (op *TransactionOperation) Collection() string {
+	return ""
+}
@@ -1,12 +1,16 @@
 package fscli
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"golang.org/x/exp/slices"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 type Parser struct {
@@ -14,6 +18,8 @@ type Parser struct {
 	curToken  Token
 	peekToken Token
 	errors    []string
+	location  *time.Location
+	now       func() time.Time
 }
 
 type ParseResult interface {
@@ -21,7 +27,7 @@ type ParseResult interface {
 }
 
 func NewParser(l *Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []string{}, location: time.UTC, now: time.Now}
 
 	// Set both curToken and peekToken
 	p.nextToken()
@@ -30,6 +36,12 @@ func NewParser(l *Lexer) *Parser {
 	return p
 }
 
+// SetLocation sets the time zone of timestamps written without one and of
+// TODAY().
+func (p *Parser) SetLocation(loc *time.Location) {
+	p.location = loc
+}
+
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
@@ -48,20 +60,596 @@ func (p *Parser) Parse() (ParseResult, error) {
 	if p.curTokenIs(COUNT) {
 		return p.parseCountOperation()
 	}
+	if p.curTokenIs(AGGREGATE) {
+		return p.parseAggregateOperation()
+	}
+	if p.curTokenIs(EXPLAIN) {
+		return p.parseExplainOperation()
+	}
+	if p.curTokenIs(INSERT) {
+		return p.parseInsertOperation()
+	}
+	if p.curTokenIs(SET) {
+		return p.parseSetOperation()
+	}
+	if p.curTokenIs(UPDATE) {
+		return p.parseUpdateOperation()
+	}
+	if p.curTokenIs(DELETE) {
+		return p.parseDeleteOperation()
+	}
+	if p.curTokenIs(DROP) {
+		return p.parseDropOperation()
+	}
+	if p.curTokenIs(COPY) {
+		return p.parseCopyOperation()
+	}
+	if p.curTokenIs(BEGIN) {
+		return p.parseTransactionOperation(OPERATION_TYPE_BEGIN)
+	}
+	if p.curTokenIs(COMMIT) {
+		return p.parseTransactionOperation(OPERATION_TYPE_COMMIT)
+	}
+	if p.curTokenIs(ROLLBACK) {
+		return p.parseTransactionOperation(OPERATION_TYPE_ROLLBACK)
+	}
 	return nil, fmt.Errorf("invalid operation: %s", p.curToken.Literal)
 }
 
+func (p *Parser) parseTransactionOperation(operationType OperationType) (*TransactionOperation, error) {
+	p.nextToken()
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+	return NewTransactionOperation(operationType), nil
+}
+
+func (p *Parser) parseInsertOperation() (*InsertOperation, error) {
+	op := &InsertOperation{}
+
+	if !p.expectPeek(INTO) {
+		return nil, fmt.Errorf("invalid: expected INTO but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
+	}
+	op.collection = normalizeFirestorePath(p.curToken.Literal)
+	if len(strings.Split(op.collection, "/"))%2 == 0 {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.curToken.Literal)
+	}
+
+	p.nextToken()
+	if p.curTokenIsWord(ID) {
+		if !p.expectPeek(STRING) {
+			return nil, fmt.Errorf("invalid: expected document ID but got %s", p.peekToken.Literal)
+		}
+		op.docId = p.curToken.Literal
+		if op.docId == "" || strings.Contains(op.docId, "/") {
+			return nil, fmt.Errorf("invalid document ID: %s", op.docId)
+		}
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(VALUES) {
+		return nil, fmt.Errorf("invalid: expected VALUES but got %s", p.curToken.Literal)
+	}
+	if !p.expectPeek(LBRACE) {
+		return nil, fmt.Errorf("invalid: expected { but got %s", p.peekToken.Literal)
+	}
+	data, err := p.parseObject()
+	if err != nil {
+		return nil, err
+	}
+	op.data = data
+
+	p.nextToken()
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return op, nil
+}
+
+func (p *Parser) parseSetOperation() (*SetOperation, error) {
+	op := &SetOperation{}
+
+	if !p.expectPeek(IDENT) {
+		return nil, fmt.Errorf("invalid: expected path but got %s", p.peekToken.Literal)
+	}
+	collection, docId, err := splitDocumentPath(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+	op.collection = collection
+	op.docId = docId
+
+	if !p.expectPeek(LBRACE) {
+		return nil, fmt.Errorf("invalid: expected { but got %s", p.peekToken.Literal)
+	}
+	data, err := p.parseObject()
+	if err != nil {
+		return nil, err
+	}
+	op.data = data
+
+	p.nextToken()
+	if p.curTokenIs(MERGE) {
+		op.merge = true
+		p.nextToken()
+	}
+
+	if p.curTokenIs(IF) {
+		precondition, err := p.parsePrecondition()
+		if err != nil {
+			return nil, err
+		}
+		op.precondition = precondition
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return op, nil
+}
+
+func (p *Parser) parseUpdateOperation() (*UpdateOperation, error) {
+	op := &UpdateOperation{}
+
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected path but got %s", p.peekToken.Literal)
+	}
+	// a collection path updates the documents matched by WHERE
+	path := normalizeFirestorePath(p.curToken.Literal)
+	bulk := len(strings.Split(path, "/"))%2 != 0
+	if bulk {
+		op.collection = path
+	} else {
+		collection, docId, err := splitDocumentPath(p.curToken.Literal)
+		if err != nil {
+			return nil, err
+		}
+		op.collection = collection
+		op.docId = docId
+	}
+
+	if !p.expectPeek(SET) {
+		return nil, fmt.Errorf("invalid: expected SET but got %s", p.peekToken.Literal)
+	}
+	updates, err := p.parseUpdates()
+	if err != nil {
+		return nil, err
+	}
+	op.updates = updates
+
+	p.nextToken()
+	if bulk {
+		query, err := p.parseWriteTargets(op.collection)
+		if err != nil {
+			return nil, err
+		}
+		op.query = query
+	} else if p.curTokenIs(IF) {
+		precondition, err := p.parseExistingPrecondition()
+		if err != nil {
+			return nil, err
+		}
+		op.precondition = precondition
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return op, nil
+}
+
+func (p *Parser) parseDeleteOperation() (*DeleteOperation, error) {
+	if p.peekTokenIs(FROM) {
+		return p.parseBulkDeleteOperation()
+	}
+
+	if !p.expectPeek(IDENT) {
+		return nil, fmt.Errorf("invalid: expected path but got %s", p.peekToken.Literal)
+	}
+	collection, docId, err := splitDocumentPath(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+	op := NewDeleteOperation(collection, docId, false)
+
+	p.nextToken()
+	if p.curTokenIs(RECURSIVE) {
+		op.recursive = true
+		p.nextToken()
+		if p.curTokenIs(IF) {
+			return nil, fmt.Errorf("invalid: IF cannot be combined with RECURSIVE")
+		}
+	} else if p.curTokenIs(IF) {
+		precondition, err := p.parseExistingPrecondition()
+		if err != nil {
+			return nil, err
+		}
+		op.precondition = precondition
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return op, nil
+}
+
+func (p *Parser) parseBulkDeleteOperation() (*DeleteOperation, error) {
+	p.nextToken()
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
+	}
+	collection := normalizeFirestorePath(p.curToken.Literal)
+	if len(strings.Split(collection, "/"))%2 == 0 {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.curToken.Literal)
+	}
+
+	p.nextToken()
+	query, err := p.parseWriteTargets(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return NewBulkDeleteOperation(query), nil
+}
+
+func (p *Parser) parseDropOperation() (*DropCollectionOperation, error) {
+	if !p.expectPeek(COLLECTION) {
+		return nil, fmt.Errorf("invalid: expected COLLECTION but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
+	}
+	collection := normalizeFirestorePath(p.curToken.Literal)
+	parts := strings.Split(collection, "/")
+	if len(parts)%2 == 0 || slices.Contains(parts, "") {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.curToken.Literal)
+	}
+
+	p.nextToken()
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return NewDropCollectionOperation(collection), nil
+}
+
+func (p *Parser) parseCopyOperation() (*CopyOperation, error) {
+	if p.peekTokenIs(COLLECTION) {
+		return p.parseCopyCollectionOperation()
+	}
+
+	if !p.expectPeek(IDENT) {
+		return nil, fmt.Errorf("invalid: expected path but got %s", p.peekToken.Literal)
+	}
+	collection, docId, err := splitDocumentPath(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectPeekWord(TO) {
+		return nil, fmt.Errorf("invalid: expected TO but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeek(IDENT) {
+		return nil, fmt.Errorf("invalid: expected path but got %s", p.peekToken.Literal)
+	}
+	toCollection, toDocId, err := splitDocumentPath(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+	op := NewCopyOperation(collection, docId, toCollection, toDocId, false)
+
+	p.nextToken()
+	if p.curTokenIs(RECURSIVE) {
+		op.recursive = true
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+	if err := checkCopyPaths(collection+"/"+docId, toCollection+"/"+toDocId, op.recursive); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (p *Parser) parseCopyCollectionOperation() (*CopyOperation, error) {
+	p.nextToken()
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
+	}
+	collection, err := normalizeCollectionPath(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectPeekWord(TO) {
+		return nil, fmt.Errorf("invalid: expected TO but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
+	}
+	toCollection, err := normalizeCollectionPath(p.curToken.Literal)
+	if err != nil {
+		return nil, err
+	}
+
+	p.nextToken()
+	query, err := p.parseWriteTargets(collection)
+	if err != nil {
+		return nil, err
+	}
+	op := NewCopyCollectionOperation(query, toCollection, false)
+
+	if p.curTokenIs(RECURSIVE) {
+		op.recursive = true
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+	if err := checkCopyPaths(collection, toCollection, op.recursive); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// checkCopyPaths rejects a copy onto itself, or a recursive copy into its
+// own subcollections, which would copy what it has just written.
+func checkCopyPaths(from string, to string, recursive bool) error {
+	if from == to || (recursive && strings.HasPrefix(to, from+"/")) {
+		return fmt.Errorf("invalid: cannot copy %s into itself", from)
+	}
+	return nil
+}
+
+// parsePrecondition parses IF EXISTS, IF NOT EXISTS and
+// IF UPDATED_AT = TIMESTAMP("time").
+func (p *Parser) parsePrecondition() (*Precondition, error) {
+	switch {
+	case p.peekTokenIs(EXISTS):
+		p.nextToken()
+		return NewPrecondition(PRECONDITION_EXISTS, time.Time{}), nil
+	case p.peekTokenIs(NOT):
+		p.nextToken()
+		if !p.expectPeek(EXISTS) {
+			return nil, fmt.Errorf("invalid: expected EXISTS but got %s", p.peekToken.Literal)
+		}
+		return NewPrecondition(PRECONDITION_NOT_EXISTS, time.Time{}), nil
+	case p.peekTokenIs(IDENT) && strings.EqualFold(p.peekToken.Literal, UPDATED_AT):
+		p.nextToken()
+		if !p.expectPeek(EQ) {
+			return nil, fmt.Errorf("invalid: expected = but got %s", p.peekToken.Literal)
+		}
+		if !p.peekTokenIs(IDENT) || p.peekToken.Literal != F_TIMESTAMP {
+			return nil, fmt.Errorf("invalid: expected TIMESTAMP but got %s", p.peekToken.Literal)
+		}
+		p.nextToken()
+		updateTime, err := p.parseTimestamp()
+		if err != nil {
+			return nil, err
+		}
+		return NewPrecondition(PRECONDITION_UPDATED_AT, updateTime), nil
+	default:
+		return nil, fmt.Errorf("invalid: expected EXISTS, NOT EXISTS or UPDATED_AT but got %s", p.peekToken.Literal)
+	}
+}
+
+// parseExistingPrecondition parses the IF clause of UPDATE and DELETE,
+// which can only apply to a document that exists.
+func (p *Parser) parseExistingPrecondition() (*Precondition, error) {
+	precondition, err := p.parsePrecondition()
+	if err != nil {
+		return nil, err
+	}
+	if precondition.preconditionType == PRECONDITION_NOT_EXISTS {
+		return nil, fmt.Errorf("invalid: IF NOT EXISTS can only be used with SET")
+	}
+	return precondition, nil
+}
+
+// parseWriteTargets parses the optional WHERE and LIMIT that select the
+// documents of collection written by a bulk statement.
+func (p *Parser) parseWriteTargets(collection string) (*QueryOperation, error) {
+	query := &QueryOperation{collection: collection}
+
+	if p.curTokenIs(WHERE) {
+		p.nextToken()
+		filters, err := p.parseWhere()
+		if err != nil {
+			return nil, err
+		}
+		query.filters = filters
+		p.nextToken()
+	}
+
+	if p.curTokenIs(LIMIT) {
+		p.nextToken()
+		limit, err := p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+		query.limit = limit
+		p.nextToken()
+	}
+
+	if p.curTokenIs(IF) {
+		return nil, fmt.Errorf("invalid: IF can only be used when writing a single document")
+	}
+
+	return query, nil
+}
+
+// parseUpdates parses the assignments after SET, such as
+// a = 1, b.c = "x", n = INCREMENT(1).
+func (p *Parser) parseUpdates() ([]Update, error) {
+	var updates []Update
+	fields := map[string]bool{}
+	for {
+		if !p.expectPeekName() {
+			return nil, fmt.Errorf("invalid: expected field but got %s", p.peekToken.Literal)
+		}
+		field := p.curToken.Literal
+		if _, err := parseFieldPath(field); err != nil {
+			return nil, err
+		}
+		if field == FieldDocumentID {
+			return nil, fmt.Errorf("invalid: %s cannot be updated", field)
+		}
+		if fields[field] {
+			return nil, fmt.Errorf("invalid: duplicate field %s", field)
+		}
+		fields[field] = true
+
+		if !p.expectPeek(EQ) {
+			return nil, fmt.Errorf("invalid: expected = but got %s", p.peekToken.Literal)
+		}
+		p.nextToken()
+
+		var value any
+		var err error
+		if p.curTokenIsTransform() {
+			value, err = p.parseTransform()
+		} else {
+			value, err = p.parseValue()
+		}
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, NewUpdate(field, value))
+
+		if !p.peekTokenIs(COMMA) {
+			return updates, nil
+		}
+		p.nextToken()
+	}
+}
+
+// transformFunctions are the functions that change a field on the server.
+var transformFunctions = []string{F_INCREMENT, F_ARRAY_UNION, F_ARRAY_REMOVE, F_DELETE_FIELD, F_SERVER_TIMESTAMP}
+
+func (p *Parser) curTokenIsTransform() bool {
+	return p.curTokenIs(IDENT) && p.peekTokenIs(LPAREN) && slices.Contains(transformFunctions, p.curToken.Literal)
+}
+
+// parseTransform parses INCREMENT(n), ARRAY_UNION(values...),
+// ARRAY_REMOVE(values...), DELETE_FIELD() or SERVER_TIMESTAMP().
+func (p *Parser) parseTransform() (Transform, error) {
+	transformType := TransformType(p.curToken.Literal)
+	if !p.expectPeek(LPAREN) {
+		return Transform{}, fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+
+	var values []any
+	if !p.peekTokenIs(RPAREN) {
+		for {
+			p.nextToken()
+			value, err := p.parseValue()
+			if err != nil {
+				return Transform{}, err
+			}
+			values = append(values, value)
+			if !p.peekTokenIs(COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+	if !p.expectPeek(RPAREN) {
+		return Transform{}, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+
+	switch transformType {
+	case TRANSFORM_INCREMENT:
+		if len(values) != 1 {
+			return Transform{}, fmt.Errorf("invalid: %s expects a number", transformType)
+		}
+		switch values[0].(type) {
+		case int64, float64:
+		default:
+			return Transform{}, fmt.Errorf("invalid: %s expects a number but got %v", transformType, values[0])
+		}
+	case TRANSFORM_ARRAY_UNION, TRANSFORM_ARRAY_REMOVE:
+		if len(values) == 0 {
+			return Transform{}, fmt.Errorf("invalid: %s expects at least one value", transformType)
+		}
+	default:
+		if len(values) != 0 {
+			return Transform{}, fmt.Errorf("invalid: %s takes no arguments", transformType)
+		}
+	}
+	return NewTransform(transformType, values), nil
+}
+
+// splitDocumentPath splits a document path such as users/abc into its
+// collection and document ID.
+func splitDocumentPath(s string) (string, string, error) {
+	path := normalizeFirestorePath(s)
+	parts := strings.Split(path, "/")
+	if len(parts)%2 != 0 || slices.Contains(parts, "") {
+		return "", "", fmt.Errorf("invalid: expected document path but got %s", s)
+	}
+	lastSlash := strings.LastIndex(path, "/")
+	return path[:lastSlash], path[lastSlash+1:], nil
+}
+
+// normalizeCollectionPath normalizes a collection path.
+func normalizeCollectionPath(s string) (string, error) {
+	path := normalizeFirestorePath(s)
+	parts := strings.Split(path, "/")
+	if len(parts)%2 == 0 || slices.Contains(parts, "") {
+		return "", fmt.Errorf("invalid: expected collection but got %s", s)
+	}
+	return path, nil
+}
+
+func (p *Parser) parseExplainOperation() (*ExplainOperation, error) {
+	op := &ExplainOperation{}
+
+	p.nextToken()
+	if p.curTokenIs(ANALYZE) {
+		op.analyze = true
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(QUERY) {
+		return nil, fmt.Errorf("invalid: expected QUERY but got %s", p.curToken.Literal)
+	}
+	query, err := p.parseQueryOperation()
+	if err != nil {
+		return nil, err
+	}
+	op.query = query
+
+	return op, nil
+}
+
 func (p *Parser) parseQueryOperation() (*QueryOperation, error) {
 	op := &QueryOperation{}
 
-	if !p.peekTokenIs(IDENT) && !p.peekTokenIs(COLLECTION_GROUP) {
+	if !p.peekTokenIsName() {
 		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
 	}
 	p.nextToken()
 
 	if p.curTokenIs(COLLECTION_GROUP) {
 		op.collectionGroup = true
-		if !p.expectPeek(IDENT) {
+		if !p.expectPeekName() {
 			return nil, fmt.Errorf("invalid: expected collection group name but got %s", p.peekToken.Literal)
 		}
 		op.collection = p.curToken.Literal
@@ -78,76 +666,237 @@ func (p *Parser) parseQueryOperation() (*QueryOperation, error) {
 		return op, nil
 	}
 
-	if p.curTokenIs(SELECT) {
-		p.nextToken()
-		selects, err := p.parseSelects()
+	if p.curTokenIs(SELECT) {
+		p.nextToken()
+		selects, aggregates, err := p.parseQuerySelects()
+		if err != nil {
+			return nil, err
+		}
+		op.selects = selects
+		op.aggregates = aggregates
+		p.nextToken()
+	}
+
+	if p.curTokenIs(WHERE) {
+		p.nextToken()
+		filters, err := p.parseWhere()
+		if err != nil {
+			p.errors = append(p.errors, err.Error())
+			return nil, err
+		}
+		op.filters = filters
+		p.nextToken()
+	}
+
+	if p.curTokenIsWord(FIND) {
+		findNearest, err := p.parseFindNearest()
+		if err != nil {
+			return nil, err
+		}
+		op.findNearest = findNearest
+		p.nextToken()
+	}
+
+	if p.curTokenIs(GROUP) {
+		p.nextToken()
+		if !p.curTokenIs(BY) {
+			return nil, fmt.Errorf("invalid: expected by but got %s", p.curToken.Type)
+		}
+		groupBys, err := p.parseGroupBy()
+		if err != nil {
+			return nil, err
+		}
+		op.groupBys = groupBys
+		p.nextToken()
+	}
+	if err := validateGroupBy(op); err != nil {
+		return nil, err
+	}
+
+	if p.curTokenIs(ORDER) {
+		p.nextToken()
+		if !p.curTokenIs(BY) {
+			return nil, fmt.Errorf("invalid: expected by but got %s", p.curToken.Type)
+		}
+		orderBys, err := p.parseOrderBy()
+		if err != nil {
+			return nil, err
+		}
+		op.orderBys = orderBys
+		p.nextToken()
+	}
+
+	if p.curTokenIs(START) {
+		cursor, err := p.parseCursor(AT, AFTER, len(op.orderBys))
 		if err != nil {
 			return nil, err
 		}
-		op.selects = selects
+		op.startCursor = cursor
+		p.nextToken()
+	}
 
-		if p.curTokenIs(EOF) {
-			return op, nil
+	if p.curTokenIs(END) {
+		cursor, err := p.parseCursor(AT, BEFORE, len(op.orderBys))
+		if err != nil {
+			return nil, err
 		}
-
+		op.endCursor = cursor
 		p.nextToken()
 	}
 
-	if p.curTokenIs(WHERE) {
+	if p.curTokenIs(LIMIT) {
 		p.nextToken()
-		for !p.curTokenIs(EOF) {
-			filter, err := p.parseFilter()
-			if err != nil {
-				p.errors = append(p.errors, err.Error())
-				return nil, err
-			}
-			if filter != nil {
-				op.filters = append(op.filters, filter)
-			}
-
-			if !p.expectPeek(AND) {
-				break
-			} else {
-				p.nextToken()
+		if p.curTokenIs(LAST) {
+			if len(op.orderBys) == 0 {
+				return nil, fmt.Errorf("invalid: LIMIT LAST requires ORDER BY")
 			}
+			op.limitToLast = true
+			p.nextToken()
 		}
-
-		if p.curTokenIs(EOF) {
-			return op, nil
+		limit, err := p.parseCount()
+		if err != nil {
+			return nil, err
 		}
+		op.limit = limit
 		p.nextToken()
 	}
 
-	if p.curTokenIs(ORDER) {
+	if p.curTokenIs(OFFSET) {
 		p.nextToken()
-		if !p.curTokenIs(BY) {
-			return nil, fmt.Errorf("invalid: expected by but got %s", p.curToken.Type)
-		}
-		orderBys, err := p.parseOrderBy()
+		offset, err := p.parseCount()
 		if err != nil {
 			return nil, err
 		}
-		op.orderBys = orderBys
+		op.offset = offset
+		p.nextToken()
+	}
 
-		if p.curTokenIs(EOF) {
-			return op, nil
+	if p.curTokenIs(AS) {
+		asOf, err := p.parseAsOf()
+		if err != nil {
+			return nil, err
 		}
+		op.asOf = asOf
 		p.nextToken()
 	}
 
-	if p.curTokenIs(LIMIT) {
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+	if err := validateFindNearest(op); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// maxFindNearestLimit is the largest LIMIT Firestore accepts for FIND NEAREST.
+const maxFindNearestLimit = 1000
+
+// parseFindNearest parses FIND NEAREST field TO VECTOR([...])
+// DISTANCE measure LIMIT n [DISTANCE FIELD name].
+func (p *Parser) parseFindNearest() (*FindNearest, error) {
+	if !p.expectPeekWord(NEAREST) {
+		return nil, fmt.Errorf("invalid: expected NEAREST but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeekName() {
+		return nil, fmt.Errorf("invalid: expected field but got %s", p.peekToken.Literal)
+	}
+	field := p.curToken.Literal
+	if _, err := parseFieldPath(field); err != nil {
+		return nil, err
+	}
+
+	if !p.expectPeekWord(TO) {
+		return nil, fmt.Errorf("invalid: expected TO but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	if !p.curTokenIs(IDENT) || p.curToken.Literal != F_VECTOR {
+		return nil, fmt.Errorf("invalid: expected VECTOR but got %s", p.curToken.Literal)
+	}
+	vector, err := p.parseVector()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.expectPeekWord(DISTANCE) {
+		return nil, fmt.Errorf("invalid: expected DISTANCE but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	var measure firestore.DistanceMeasure
+	if p.curTokenIsWord(COSINE) {
+		measure = firestore.DistanceMeasureCosine
+	} else if p.curTokenIsWord(EUCLIDEAN) {
+		measure = firestore.DistanceMeasureEuclidean
+	} else if p.curTokenIsWord(DOT_PRODUCT) {
+		measure = firestore.DistanceMeasureDotProduct
+	} else {
+		return nil, fmt.Errorf("invalid: expected COSINE, EUCLIDEAN or DOT_PRODUCT but got %s", p.curToken.Literal)
+	}
+
+	if !p.expectPeek(LIMIT) {
+		return nil, fmt.Errorf("invalid: FIND NEAREST requires LIMIT but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	limit, err := p.parseCount()
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxFindNearestLimit {
+		return nil, fmt.Errorf("invalid: FIND NEAREST LIMIT must be between 1 and %d", maxFindNearestLimit)
+	}
+
+	findNearest := NewFindNearest(field, vector, measure, limit, "")
+	if p.peekTokenIsWord(DISTANCE) {
 		p.nextToken()
-		if !p.curTokenIs(INT) {
-			return nil, fmt.Errorf("invalid: expected int but got %s", p.curToken.Type)
+		if !p.expectPeekWord(FIELD) {
+			return nil, fmt.Errorf("invalid: expected FIELD but got %s", p.peekToken.Literal)
 		}
-		limit, err := strconv.Atoi(p.curToken.Literal)
-		if err != nil {
-			return nil, fmt.Errorf("invalid: expected int but got %s", p.curToken.Literal)
+		p.nextToken()
+		if !p.curTokenIsName() && !p.curTokenIs(STRING) {
+			return nil, fmt.Errorf("invalid: expected distance field but got %s", p.curToken.Literal)
 		}
-		op.limit = limit
+		findNearest.distanceField = p.curToken.Literal
 	}
+	return findNearest, nil
+}
 
-	return op, nil
+// validateFindNearest rejects the clauses Firestore does not allow with
+// a vector search.
+func validateFindNearest(op *QueryOperation) error {
+	if op.findNearest == nil {
+		return nil
+	}
+
+	var clause string
+	if op.IsGroupBy() || len(op.aggregates) > 0 {
+		clause = "GROUP BY"
+	} else if len(op.orderBys) > 0 {
+		clause = "ORDER BY"
+	} else if op.startCursor != nil {
+		clause = "START"
+	} else if op.endCursor != nil {
+		clause = "END"
+	} else if op.limit > 0 {
+		clause = "LIMIT"
+	} else if op.offset > 0 {
+		clause = "OFFSET"
+	} else {
+		return nil
+	}
+	return fmt.Errorf("invalid: FIND NEAREST cannot be combined with %s", clause)
+}
+
+// parseCount parses a non-negative int such as the LIMIT count.
+func (p *Parser) parseCount() (int, error) {
+	if !p.curTokenIs(INT) {
+		return 0, fmt.Errorf("invalid: expected int but got %s", p.curToken.Type)
+	}
+	n, err := strconv.Atoi(p.curToken.Literal)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid: expected non-negative int but got %s", p.curToken.Literal)
+	}
+	return n, nil
 }
 
 func (p *Parser) parseGetOperation() (*GetOperation, error) {
@@ -174,22 +923,48 @@ func (p *Parser) parseGetOperation() (*GetOperation, error) {
 			return nil, err
 		}
 		op.selects = selects
+		p.nextToken()
+	}
+
+	if p.curTokenIs(AS) {
+		asOf, err := p.parseAsOf()
+		if err != nil {
+			return nil, err
+		}
+		op.asOf = asOf
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
 	}
 
 	return op, nil
 }
 
+// parseAsOf parses AS OF TIMESTAMP(...), the time to read the data at.
+func (p *Parser) parseAsOf() (time.Time, error) {
+	if !p.expectPeek(OF) {
+		return time.Time{}, fmt.Errorf("invalid: expected OF but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	if !p.curTokenIsTimeFunction() {
+		return time.Time{}, fmt.Errorf("invalid: expected TIMESTAMP, NOW or TODAY but got %s", p.curToken.Literal)
+	}
+	return p.parseTimeExpression()
+}
+
 func (p *Parser) parseCountOperation() (*CountOperation, error) {
 	op := &CountOperation{}
 
-	if !p.peekTokenIs(IDENT) && !p.peekTokenIs(COLLECTION_GROUP) {
+	if !p.peekTokenIsName() {
 		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
 	}
 	p.nextToken()
 
 	if p.curTokenIs(COLLECTION_GROUP) {
 		op.collectionGroup = true
-		if !p.expectPeek(IDENT) {
+		if !p.expectPeekName() {
 			return nil, fmt.Errorf("invalid: expected collection group name but got %s", p.peekToken.Literal)
 		}
 		op.collection = p.curToken.Literal
@@ -208,55 +983,353 @@ func (p *Parser) parseCountOperation() (*CountOperation, error) {
 
 	if p.curTokenIs(WHERE) {
 		p.nextToken()
-		for !p.curTokenIs(EOF) {
+		filters, err := p.parseWhere()
+		if err != nil {
+			p.errors = append(p.errors, err.Error())
+			return nil, err
+		}
+		op.filters = filters
+		p.nextToken()
+	}
 
-			filter, err := p.parseFilter()
-			if err != nil {
-				p.errors = append(p.errors, err.Error())
-				return nil, err
-			}
-			if filter != nil {
-				op.filters = append(op.filters, filter)
-			}
+	if p.curTokenIs(AS) {
+		asOf, err := p.parseAsOf()
+		if err != nil {
+			return nil, err
+		}
+		op.asOf = asOf
+		p.nextToken()
+	}
 
-			if !p.expectPeek(AND) {
-				break
-			} else {
-				p.nextToken()
-			}
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
+	return op, nil
+}
+
+func (p *Parser) parseAggregateOperation() (*AggregateOperation, error) {
+	op := &AggregateOperation{}
+
+	if !p.peekTokenIsName() {
+		return nil, fmt.Errorf("invalid: expected collection but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+
+	if p.curTokenIs(COLLECTION_GROUP) {
+		op.collectionGroup = true
+		if !p.expectPeekName() {
+			return nil, fmt.Errorf("invalid: expected collection group name but got %s", p.peekToken.Literal)
+		}
+		op.collection = p.curToken.Literal
+		if err := validateCollectionGroupName(op.collection); err != nil {
+			return nil, err
 		}
+	} else {
+		op.collection = normalizeFirestorePath(p.curToken.Literal)
+	}
 
-		if p.curTokenIs(EOF) {
-			return op, nil
+	p.nextToken()
+	aggregates, err := p.parseAggregates()
+	if err != nil {
+		return nil, err
+	}
+	op.aggregates = aggregates
+	p.nextToken()
+
+	if p.curTokenIs(WHERE) {
+		p.nextToken()
+		filters, err := p.parseWhere()
+		if err != nil {
+			p.errors = append(p.errors, err.Error())
+			return nil, err
 		}
+		op.filters = filters
 		p.nextToken()
 	}
 
+	if p.curTokenIs(AS) && p.peekTokenIs(OF) {
+		return nil, fmt.Errorf("invalid: AS OF is not supported with AGGREGATE")
+	}
+
+	if !p.curTokenIs(EOF) {
+		return nil, fmt.Errorf("invalid: unexpected %s", p.curToken.Literal)
+	}
+
 	return op, nil
 }
 
+func (p *Parser) parseAggregates() ([]Aggregate, error) {
+	aggregates := []Aggregate{}
+	aliases := map[string]bool{}
+	for {
+		aggregate, err := p.parseAggregate()
+		if err != nil {
+			return nil, err
+		}
+		if aggregate.function == AGGREGATE_MIN || aggregate.function == AGGREGATE_MAX {
+			return nil, fmt.Errorf("invalid: %s is only supported with QUERY ... GROUP BY", aggregate.function)
+		}
+		if aliases[aggregate.alias] {
+			return nil, fmt.Errorf("invalid: duplicate aggregate %s", aggregate.alias)
+		}
+		aliases[aggregate.alias] = true
+		aggregates = append(aggregates, aggregate)
+
+		if !p.peekTokenIs(COMMA) {
+			return aggregates, nil
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+}
+
+// parseAggregate parses COUNT(*), SUM(field), AVG(field), MIN(field) or
+// MAX(field) with an optional AS alias. Without an alias the aggregate is named after its expression.
+func (p *Parser) parseAggregate() (Aggregate, error) {
+	var function AggregateFunction
+	switch {
+	case p.curTokenIs(COUNT):
+		function = AGGREGATE_COUNT
+	case p.curTokenIs(IDENT) && p.curToken.Literal == F_SUM:
+		function = AGGREGATE_SUM
+	case p.curTokenIs(IDENT) && p.curToken.Literal == F_AVG:
+		function = AGGREGATE_AVG
+	case p.curTokenIs(IDENT) && p.curToken.Literal == F_MIN:
+		function = AGGREGATE_MIN
+	case p.curTokenIs(IDENT) && p.curToken.Literal == F_MAX:
+		function = AGGREGATE_MAX
+	default:
+		return Aggregate{}, fmt.Errorf("invalid: expected aggregation but got %s", p.curToken.Literal)
+	}
+
+	if !p.expectPeek(LPAREN) {
+		return Aggregate{}, fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+
+	var field string
+	if function == AGGREGATE_COUNT {
+		if !p.expectPeek(ASTERISK) {
+			return Aggregate{}, fmt.Errorf("invalid: expected * but got %s", p.peekToken.Literal)
+		}
+	} else {
+		if !p.expectPeekName() {
+			return Aggregate{}, fmt.Errorf("invalid: expected field but got %s", p.peekToken.Literal)
+		}
+		field = p.curToken.Literal
+		if _, err := parseFieldPath(field); err != nil {
+			return Aggregate{}, err
+		}
+	}
+
+	if !p.expectPeek(RPAREN) {
+		return Aggregate{}, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+
+	arg := field
+	if function == AGGREGATE_COUNT {
+		arg = ASTERISK
+	}
+	alias := fmt.Sprintf("%s(%s)", function, arg)
+	// AS OF after an aggregation is the read time, not an alias
+	if p.peekTokenIs(AS) && !p.peekSecondTokenIs(OF) {
+		p.nextToken()
+		p.nextToken()
+		if !p.curTokenIsName() && !p.curTokenIs(STRING) {
+			return Aggregate{}, fmt.Errorf("invalid: expected alias but got %s", p.curToken.Literal)
+		}
+		alias = p.curToken.Literal
+	}
+
+	return NewAggregate(function, field, alias), nil
+}
+
 func (p *Parser) parseSelects() ([]string, error) {
 	var selects []string
 	for {
-		if !p.curTokenIs(IDENT) {
+		if !p.curTokenIsName() {
 			return nil, fmt.Errorf("invalid: expected field but got %s", p.curToken.Literal)
 		}
+		if _, err := parseFieldPath(p.curToken.Literal); err != nil {
+			return nil, err
+		}
 		selects = append(selects, p.curToken.Literal)
 
 		if !p.expectPeek(COMMA) {
 			break
 		}
-		p.nextToken()
+		p.nextToken()
+	}
+
+	return selects, nil
+}
+
+// parseQuerySelects parses the SELECT list of QUERY, which may mix fields
+// and aggregations for GROUP BY.
+func (p *Parser) parseQuerySelects() ([]string, []Aggregate, error) {
+	var selects []string
+	var aggregates []Aggregate
+	names := map[string]bool{}
+	for {
+		if (p.curTokenIs(COUNT) || p.curTokenIs(IDENT)) && p.peekTokenIs(LPAREN) {
+			aggregate, err := p.parseAggregate()
+			if err != nil {
+				return nil, nil, err
+			}
+			if names[aggregate.alias] {
+				return nil, nil, fmt.Errorf("invalid: duplicate aggregate %s", aggregate.alias)
+			}
+			names[aggregate.alias] = true
+			aggregates = append(aggregates, aggregate)
+		} else {
+			if !p.curTokenIsName() {
+				return nil, nil, fmt.Errorf("invalid: expected field but got %s", p.curToken.Literal)
+			}
+			if _, err := parseFieldPath(p.curToken.Literal); err != nil {
+				return nil, nil, err
+			}
+			selects = append(selects, p.curToken.Literal)
+		}
+
+		if !p.expectPeek(COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	return selects, aggregates, nil
+}
+
+func (p *Parser) parseGroupBy() ([]string, error) {
+	groupBys := []string{}
+	for {
+		p.nextToken()
+		if !p.curTokenIsName() {
+			return nil, fmt.Errorf("invalid: expected field but got %s", p.curToken.Literal)
+		}
+		if _, err := parseFieldPath(p.curToken.Literal); err != nil {
+			return nil, err
+		}
+		groupBys = append(groupBys, p.curToken.Literal)
+
+		if !p.peekTokenIs(COMMA) {
+			return groupBys, nil
+		}
+		p.nextToken()
+	}
+}
+
+// validateGroupBy checks that aggregations come with GROUP BY and that
+// every selected field is grouped.
+func validateGroupBy(op *QueryOperation) error {
+	if len(op.groupBys) == 0 {
+		if len(op.aggregates) > 0 {
+			return fmt.Errorf("invalid: %s requires GROUP BY", op.aggregates[0].alias)
+		}
+		return nil
+	}
+	for _, field := range op.selects {
+		if !slices.Contains(op.groupBys, field) {
+			return fmt.Errorf("invalid: %s must appear in GROUP BY", field)
+		}
+	}
+	for _, aggregate := range op.aggregates {
+		if slices.Contains(op.selects, aggregate.alias) {
+			return fmt.Errorf("invalid: duplicate column %s", aggregate.alias)
+		}
+	}
+	return nil
+}
+
+// parseWhere parses the conditions of a WHERE clause. Top-level AND
+// conditions are returned as separate filters.
+func (p *Parser) parseWhere() ([]Filter, error) {
+	if p.curTokenIs(EOF) {
+		return nil, nil
+	}
+
+	filter, err := p.parseOrFilter()
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := filter.(*CompositeFilter); ok && f.Operator() == OPERATOR_AND {
+		return f.Filters(), nil
+	}
+	return []Filter{filter}, nil
+}
+
+func (p *Parser) parseOrFilter() (Filter, error) {
+	filter, err := p.parseAndFilter()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekTokenIs(OR) {
+		return filter, nil
+	}
+
+	filters := []Filter{filter}
+	for p.peekTokenIs(OR) {
+		p.nextToken()
+		p.nextToken()
+		filter, err := p.parseAndFilter()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return NewOrFilter(filters), nil
+}
+
+func (p *Parser) parseAndFilter() (Filter, error) {
+	filter, err := p.parseFilterTerm()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekTokenIs(AND) {
+		return filter, nil
+	}
+
+	filters := []Filter{filter}
+	for p.peekTokenIs(AND) {
+		p.nextToken()
+		p.nextToken()
+		filter, err := p.parseFilterTerm()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
 	}
+	return NewAndFilter(filters), nil
+}
 
-	return selects, nil
+func (p *Parser) parseFilterTerm() (Filter, error) {
+	if !p.curTokenIs(LPAREN) {
+		return p.parseFilter()
+	}
+
+	p.nextToken()
+	filter, err := p.parseOrFilter()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectPeek(RPAREN) {
+		return nil, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+	return filter, nil
 }
 
 func (p *Parser) parseFilter() (Filter, error) {
 	field := p.curToken.Literal
+	if _, err := parseFieldPath(field); err != nil {
+		return nil, err
+	}
 
 	p.nextToken()
 
+	if p.curTokenIs(IS) {
+		return p.parseIsFilter(field)
+	}
+
 	var operator Operator
 	if p.curTokenIs(EQ) {
 		operator = OPERATOR_EQ
@@ -272,6 +1345,11 @@ func (p *Parser) parseFilter() (Filter, error) {
 		operator = OPERATOR_LTE
 	} else if p.curTokenIs(IN) {
 		operator = OPERATOR_IN
+	} else if p.curTokenIs(NOT_IN) {
+		operator = OPERATOR_NOT_IN
+	} else if p.curTokenIs(NOT) && p.peekTokenIs(IN) {
+		p.nextToken()
+		operator = OPERATOR_NOT_IN
 	} else if p.curTokenIs(ARRAY_CONTAINS) {
 		operator = OPERATOR_ARRAY_CONTAINS
 	} else if p.curTokenIs(ARRAY_CONTAINS_ANY) {
@@ -282,111 +1360,490 @@ func (p *Parser) parseFilter() (Filter, error) {
 
 	p.nextToken()
 
-	if p.curTokenIs(LBRACKET) {
-		return p.parseArrayFilter(field, operator)
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return newFilter(field, operator, value)
+}
+
+// newFilter wraps a parsed value in the Filter for its type.
+func newFilter(field string, operator Operator, value any) (Filter, error) {
+	if _, ok := value.([]any); !ok && operator == OPERATOR_NOT_IN {
+		return nil, fmt.Errorf("invalid: expected array for NOT_IN but got %v", value)
 	}
 
+	switch v := value.(type) {
+	case int64:
+		return NewIntFilter(field, operator, v), nil
+	case float64:
+		if math.IsNaN(v) {
+			return NewNaNFilter(field, operator), nil
+		}
+		return NewFloatFilter(field, operator, v), nil
+	case string:
+		return NewStringFilter(field, operator, v), nil
+	case bool:
+		return NewBoolFilter(field, operator, v), nil
+	case nil:
+		return NewNullFilter(field, operator), nil
+	case time.Time:
+		return NewTimestampFilter(field, operator, v), nil
+	case []any:
+		return NewArrayFilter(field, operator, v), nil
+	case firestore.Vector64:
+		return NewVectorFilter(field, operator, v), nil
+	case Ref:
+		return NewRefFilter(field, operator, v), nil
+	case *latlng.LatLng:
+		return NewGeoPointFilter(field, operator, v), nil
+	case []byte:
+		return NewBytesFilter(field, operator, v), nil
+	default:
+		return nil, fmt.Errorf("invalid filter value: %v", v)
+	}
+}
+
+// valueFunctions are the functions that build a value, such as
+// TIMESTAMP("...").
+var valueFunctions = []string{F_TIMESTAMP, F_NOW, F_TODAY, F_VECTOR, F_REF, F_GEOPOINT, F_BYTES}
+
+// parseValue parses a literal such as 1, 2.5, "a", true, null, NaN,
+// TIMESTAMP("..."), NOW() - INTERVAL '...', VECTOR([...]), REF("..."), GEOPOINT(lat, lng),
+// BYTES("...") or an array or object of them.
+func (p *Parser) parseValue() (any, error) {
+	if p.curTokenIs(LBRACKET) {
+		return p.parseArray()
+	}
+	if p.curTokenIs(LBRACE) {
+		return p.parseObject()
+	}
 	if p.curTokenIs(INT) {
-		n, err := strconv.Atoi(p.curToken.Literal)
+		n, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
 		if err != nil {
 			return nil, fmt.Errorf("invalid int value: %s", p.curToken.Literal)
 		}
-		return NewIntFilter(field, operator, n), nil
+		return n, nil
 	}
 	if p.curTokenIs(FLOAT) {
 		n, err := strconv.ParseFloat(p.curToken.Literal, 64)
 		if err != nil {
 			return nil, fmt.Errorf("invalid float value: %s", p.curToken.Literal)
 		}
-		return NewFloatFilter(field, operator, n), nil
+		return n, nil
 	}
 	if p.curTokenIs(STRING) {
-		return NewStringFilter(field, operator, p.curToken.Literal), nil
+		return p.curToken.Literal, nil
+	}
+	if p.curTokenIs(TRUE) || p.curTokenIs(FALSE) {
+		return p.curTokenIs(TRUE), nil
+	}
+	if p.curTokenIs(NULL) {
+		return nil, nil
+	}
+	if p.curTokenIs(NAN) {
+		return math.NaN(), nil
+	}
+	if p.curTokenIsTimeFunction() {
+		return p.parseTimeExpression()
 	}
-	if p.curTokenIs(IDENT) && p.curToken.Literal == F_TIMESTAMP {
-		return p.parseTimestampFilter(field, operator)
+	if p.curTokenIs(IDENT) && p.curToken.Literal == F_VECTOR {
+		return p.parseVector()
 	}
-	return nil, fmt.Errorf("invalid filter value: %s", p.curToken.Literal)
+	if p.curTokenIs(IDENT) && p.curToken.Literal == F_REF {
+		return p.parseRef()
+	}
+	if p.curTokenIs(IDENT) && p.curToken.Literal == F_GEOPOINT {
+		return p.parseGeoPoint()
+	}
+	if p.curTokenIs(IDENT) && p.curToken.Literal == F_BYTES {
+		return p.parseBytes()
+	}
+	return nil, fmt.Errorf("invalid value: %s", p.curToken.Literal)
+}
+
+func (p *Parser) parseIsFilter(field string) (Filter, error) {
+	operator := OPERATOR_EQ
+	if p.peekTokenIs(NOT) {
+		p.nextToken()
+		operator = OPERATOR_NOT_EQ
+	}
+
+	p.nextToken()
+	if p.curTokenIs(NULL) {
+		return NewNullFilter(field, operator), nil
+	}
+	if p.curTokenIs(NAN) {
+		return NewNaNFilter(field, operator), nil
+	}
+	return nil, fmt.Errorf("invalid: expected NULL or NAN but got %s", p.curToken.Literal)
 }
 
 func (p *Parser) parseOrderBy() ([]OrderBy, error) {
 	orderBys := []OrderBy{}
-	for !p.curTokenIs(EOF) {
+	for {
 		p.nextToken()
-		if !p.curTokenIs(IDENT) {
+		if !p.curTokenIsName() {
 			return nil, fmt.Errorf("invalid: expected field but got %s", p.curToken.Literal)
 		}
 		field := p.curToken.Literal
-
-		var fsDir firestore.Direction = firestore.Asc
-		if !p.expectPeek(COMMA) && !p.expectPeek(ASC) && !p.expectPeek(DESC) {
-			orderBys = append(orderBys, OrderBy{field, fsDir})
-			break
+		if _, err := parseFieldPath(field); err != nil {
+			return nil, err
 		}
 
-		if p.curTokenIs(ASC) {
-			fsDir = firestore.Asc
-		} else if p.curTokenIs(DESC) {
+		var fsDir firestore.Direction = firestore.Asc
+		if p.peekTokenIs(ASC) {
+			p.nextToken()
+		} else if p.peekTokenIs(DESC) {
+			p.nextToken()
 			fsDir = firestore.Desc
 		}
 
 		orderBys = append(orderBys, OrderBy{field, fsDir})
 
-		if !p.expectPeek(COMMA) {
+		if !p.peekTokenIs(COMMA) {
 			return orderBys, nil
 		}
+		p.nextToken()
+	}
+}
+
+// parseCursor parses START AT/AFTER and END AT/BEFORE followed by
+// a document path or values matching the ORDER BY fields.
+func (p *Parser) parseCursor(inclusive TokenType, exclusive TokenType, orderBys int) (*Cursor, error) {
+	clause := p.curToken.Literal
+	p.nextToken()
+	if !p.curTokenIs(inclusive) && !p.curTokenIs(exclusive) {
+		return nil, fmt.Errorf("invalid: expected %s or %s but got %s", inclusive, exclusive, p.curToken.Literal)
+	}
+	isInclusive := p.curTokenIs(inclusive)
+	p.nextToken()
+
+	if p.curTokenIs(IDENT) && !slices.Contains(valueFunctions, p.curToken.Literal) {
+		return NewDocumentCursor(isInclusive, normalizeFirestorePath(p.curToken.Literal)), nil
+	}
+
+	var values []any
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken()
+		p.nextToken()
+	}
+	if len(values) > orderBys {
+		return nil, fmt.Errorf("invalid: %s has %d values but ORDER BY has %d fields", clause, len(values), orderBys)
 	}
-	return orderBys, nil
+	return NewValuesCursor(isInclusive, values), nil
 }
 
-func (p *Parser) parseArrayFilter(field string, operator Operator) (Filter, error) {
+func (p *Parser) parseArray() ([]any, error) {
 	var values []any
 	p.nextToken()
 	for !p.curTokenIs(RBRACKET) {
-		if p.curTokenIs(STRING) {
-			values = append(values, p.curToken.Literal)
-		} else if p.curTokenIs(INT) {
-			n, err := strconv.Atoi(p.curToken.Literal)
-			if err != nil {
-				return nil, fmt.Errorf("invalid int value: %s", p.curToken.Literal)
-			}
-			values = append(values, n)
-		} else if p.curTokenIs(FLOAT) {
-			n, err := strconv.ParseFloat(p.curToken.Literal, 64)
-			if err != nil {
-				return nil, fmt.Errorf("invalid float value: %s", p.curToken.Literal)
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		if !p.peekTokenIs(COMMA) {
+			if !p.expectPeek(RBRACKET) {
+				return nil, fmt.Errorf("invalid: expected ] but got %s", p.peekToken.Literal)
 			}
-			values = append(values, n)
-		} else {
-			return nil, fmt.Errorf("invalid array filter value: %s", p.curToken.Literal)
+			break
 		}
+		p.nextToken()
+		p.nextToken()
+	}
+	return values, nil
+}
 
-		if !p.expectPeek(COMMA) {
+// parseObject parses an object such as {name: "a", "full name": "a b",
+// address: {city: "Tokyo"}}. Keys are field names, not paths.
+func (p *Parser) parseObject() (map[string]any, error) {
+	object := map[string]any{}
+	p.nextToken()
+	for !p.curTokenIs(RBRACE) {
+		key, err := p.parseObjectKey()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := object[key]; ok {
+			return nil, fmt.Errorf("invalid: duplicate key %s", key)
+		}
+		if !p.expectPeek(COLON) {
+			return nil, fmt.Errorf("invalid: expected : but got %s", p.peekToken.Literal)
+		}
+		p.nextToken()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+
+		if !p.peekTokenIs(COMMA) {
+			if !p.expectPeek(RBRACE) {
+				return nil, fmt.Errorf("invalid: expected } but got %s", p.peekToken.Literal)
+			}
 			break
 		}
 		p.nextToken()
+		p.nextToken()
+	}
+	return object, nil
+}
+
+// parseObjectKey parses a key of an object: a name, a quoted string,
+// a backtick-quoted name or a keyword.
+func (p *Parser) parseObjectKey() (string, error) {
+	if p.curTokenIs(STRING) {
+		return p.curToken.Literal, nil
+	}
+	if !p.curTokenIsName() {
+		return "", fmt.Errorf("invalid: expected key but got %s", p.curToken.Literal)
+	}
+	fp, err := parseFieldPath(p.curToken.Literal)
+	if err != nil {
+		return "", err
+	}
+	if len(fp) != 1 {
+		return "", fmt.Errorf("invalid: key %s must be a single field name, quote it to use dots", p.curToken.Literal)
+	}
+	return fp[0], nil
+}
+
+// parseTimeExpression parses TIMESTAMP(...), NOW() or TODAY() followed by
+// any number of + INTERVAL '...' and - INTERVAL '...'.
+func (p *Parser) parseTimeExpression() (time.Time, error) {
+	var t time.Time
+	var err error
+	switch p.curToken.Literal {
+	case F_TIMESTAMP:
+		t, err = p.parseTimestamp()
+	case F_NOW:
+		t, err = p.parseNow()
+	case F_TODAY:
+		t, err = p.parseToday()
+	default:
+		err = fmt.Errorf("invalid: expected TIMESTAMP, NOW or TODAY but got %s", p.curToken.Literal)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for p.peekTokenIs(PLUS) || p.peekTokenIs(MINUS) {
+		p.nextToken()
+		sign := 1
+		if p.curTokenIs(MINUS) {
+			sign = -1
+		}
+		if !p.expectPeek(INTERVAL) {
+			return time.Time{}, fmt.Errorf("invalid: expected INTERVAL but got %s", p.peekToken.Literal)
+		}
+		if !p.expectPeek(STRING) {
+			return time.Time{}, fmt.Errorf("invalid: expected interval string but got %s", p.peekToken.Literal)
+		}
+		t, err = addInterval(t, p.curToken.Literal, sign)
+		if err != nil {
+			return time.Time{}, err
+		}
 	}
-	return NewArrayFilter(field, operator, values), nil
+	return t, nil
 }
 
-func (p *Parser) parseTimestampFilter(field string, operator Operator) (Filter, error) {
+// parseTimestamp parses TIMESTAMP("time") and TIMESTAMP("time", "zone").
+// The zone applies only when the time has no offset of its own.
+func (p *Parser) parseTimestamp() (time.Time, error) {
 	if !p.expectPeek(LPAREN) {
-		return nil, fmt.Errorf("invalid: expected ( but got %s", p.curToken.Literal)
+		return time.Time{}, fmt.Errorf("invalid: expected ( but got %s", p.curToken.Literal)
 	}
 	p.nextToken()
 	if !p.curTokenIs(STRING) {
-		return nil, fmt.Errorf("invalid: expected string but got %s", p.curToken.Literal)
+		return time.Time{}, fmt.Errorf("invalid: expected string but got %s", p.curToken.Literal)
 	}
 	timeStr := p.curToken.Literal
+
+	loc := p.location
+	if p.peekTokenIs(COMMA) {
+		p.nextToken()
+		var err error
+		loc, err = p.parseZone()
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if !p.expectPeek(RPAREN) {
+		return time.Time{}, fmt.Errorf("invalid: expected ) but got %s", p.curToken.Literal)
+	}
+
+	t, err := parseTime(timeStr, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp value: %s", timeStr)
+	}
+	return t, nil
+}
+
+// parseNow parses NOW().
+func (p *Parser) parseNow() (time.Time, error) {
+	if !p.expectPeek(LPAREN) {
+		return time.Time{}, fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeek(RPAREN) {
+		return time.Time{}, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+	return p.now().In(p.location), nil
+}
+
+// parseToday parses TODAY() and TODAY("zone"), the start of the current day.
+func (p *Parser) parseToday() (time.Time, error) {
+	if !p.expectPeek(LPAREN) {
+		return time.Time{}, fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+	loc := p.location
+	if p.peekTokenIs(STRING) {
+		var err error
+		loc, err = p.parseZone()
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if !p.expectPeek(RPAREN) {
+		return time.Time{}, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+	return startOfDay(p.now(), loc), nil
+}
+
+// parseZone parses the string time zone argument of TIMESTAMP and TODAY.
+func (p *Parser) parseZone() (*time.Location, error) {
+	if !p.expectPeek(STRING) {
+		return nil, fmt.Errorf("invalid: expected time zone but got %s", p.peekToken.Literal)
+	}
+	return loadLocation(p.curToken.Literal)
+}
+
+// parseVector parses VECTOR([1.0, 2.0, ...]).
+func (p *Parser) parseVector() (firestore.Vector64, error) {
+	if !p.expectPeek(LPAREN) {
+		return nil, fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeek(LBRACKET) {
+		return nil, fmt.Errorf("invalid: expected [ but got %s", p.peekToken.Literal)
+	}
+	values, err := p.parseArray()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectPeek(RPAREN) {
+		return nil, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("invalid: VECTOR must not be empty")
+	}
+
+	vector := make(firestore.Vector64, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case int64:
+			vector = append(vector, float64(v))
+		case float64:
+			vector = append(vector, v)
+		default:
+			return nil, fmt.Errorf("invalid: VECTOR expects numbers but got %v", v)
+		}
+	}
+	return vector, nil
+}
+
+// parseStringArgument parses the ("...") of a function taking one string.
+func (p *Parser) parseStringArgument() (string, error) {
+	if !p.expectPeek(LPAREN) {
+		return "", fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+	if !p.expectPeek(STRING) {
+		return "", fmt.Errorf("invalid: expected string but got %s", p.peekToken.Literal)
+	}
+	s := p.curToken.Literal
+	if !p.expectPeek(RPAREN) {
+		return "", fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+	return s, nil
+}
+
+// parseRef parses REF("collection/docId").
+func (p *Parser) parseRef() (Ref, error) {
+	s, err := p.parseStringArgument()
+	if err != nil {
+		return Ref{}, err
+	}
+	path := normalizeFirestorePath(s)
+	parts := strings.Split(path, "/")
+	if path == "" || len(parts)%2 != 0 || slices.Contains(parts, "") {
+		return Ref{}, fmt.Errorf("invalid: REF expects a document path but got %s", s)
+	}
+	return NewRef(path), nil
+}
+
+// parseGeoPoint parses GEOPOINT(latitude, longitude).
+func (p *Parser) parseGeoPoint() (*latlng.LatLng, error) {
+	if !p.expectPeek(LPAREN) {
+		return nil, fmt.Errorf("invalid: expected ( but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	lat, err := p.parseNumber()
+	if err != nil {
+		return nil, err
+	}
+	if !p.expectPeek(COMMA) {
+		return nil, fmt.Errorf("invalid: expected , but got %s", p.peekToken.Literal)
+	}
+	p.nextToken()
+	lng, err := p.parseNumber()
+	if err != nil {
+		return nil, err
+	}
 	if !p.expectPeek(RPAREN) {
-		return nil, fmt.Errorf("invalid: expected ) but got %s", p.curToken.Literal)
+		return nil, fmt.Errorf("invalid: expected ) but got %s", p.peekToken.Literal)
+	}
+
+	if lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("invalid: latitude must be between -90 and 90 but got %v", lat)
+	}
+	if lng < -180 || lng > 180 {
+		return nil, fmt.Errorf("invalid: longitude must be between -180 and 180 but got %v", lng)
+	}
+	return &latlng.LatLng{Latitude: lat, Longitude: lng}, nil
+}
+
+// parseNumber parses an int or float as float64.
+func (p *Parser) parseNumber() (float64, error) {
+	if !p.curTokenIs(INT) && !p.curTokenIs(FLOAT) {
+		return 0, fmt.Errorf("invalid: expected number but got %s", p.curToken.Literal)
 	}
+	n, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number value: %s", p.curToken.Literal)
+	}
+	return n, nil
+}
 
-	t, err := parseTime(timeStr)
+// parseBytes parses BYTES("base64").
+func (p *Parser) parseBytes() ([]byte, error) {
+	s, err := p.parseStringArgument()
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
-		return nil, fmt.Errorf("invalid timestamp value: %s", timeStr)
+		return nil, fmt.Errorf("invalid bytes value: %s", s)
 	}
-	return NewTimestampFilter(field, operator, t), nil
+	return b, nil
 }
 
 func (p *Parser) parseMetacommand() (Metacommand, error) {
@@ -416,6 +1873,44 @@ func (p *Parser) parseMetacommand() (Metacommand, error) {
 		}
 	}
 
+	if p.curTokenIs(SET_AS_OF) {
+		p.nextToken()
+		if p.curTokenIs(IDENT) && p.curToken.Literal == "off" {
+			return &MetacommandAsOf{}, nil
+		}
+		if p.curTokenIsTimeFunction() {
+			asOf, err := p.parseTimeExpression()
+			if err != nil {
+				return nil, err
+			}
+			return &MetacommandAsOf{asOf: asOf}, nil
+		}
+		return nil, fmt.Errorf("invalid: expected TIMESTAMP or off but got %s", p.curToken.Literal)
+	}
+
+	if p.curTokenIs(SET_TIMEZONE) {
+		p.nextToken()
+		if p.curTokenIs(EOF) {
+			return &MetacommandTimezone{}, nil
+		}
+		if p.curTokenIs(IDENT) || p.curTokenIs(STRING) {
+			loc, err := loadLocation(p.curToken.Literal)
+			if err != nil {
+				return nil, err
+			}
+			return &MetacommandTimezone{location: loc}, nil
+		}
+		return nil, fmt.Errorf("invalid: expected time zone but got %s", p.curToken.Literal)
+	}
+
+	if p.curTokenIs(SET_DRY_RUN) {
+		p.nextToken()
+		if p.curTokenIs(IDENT) && (p.curToken.Literal == "on" || p.curToken.Literal == "off") {
+			return &MetacommandDryRun{on: p.curToken.Literal == "on"}, nil
+		}
+		return nil, fmt.Errorf("invalid: expected on/off but got %s", p.curToken.Literal)
+	}
+
 	return nil, fmt.Errorf("invalid metacommand: %s", p.curToken.Literal)
 }
 
@@ -430,9 +1925,26 @@ func (p *Parser) curTokenIsMetacommand() bool {
 	if p.curTokenIs(PAGER) {
 		return true
 	}
+	if p.curTokenIs(SET_AS_OF) {
+		return true
+	}
+	if p.curTokenIs(SET_TIMEZONE) {
+		return true
+	}
+	if p.curTokenIs(SET_DRY_RUN) {
+		return true
+	}
 	return false
 }
 
+func (p *Parser) curTokenIsTimeFunction() bool {
+	if !p.curTokenIs(IDENT) {
+		return false
+	}
+	lit := p.curToken.Literal
+	return lit == F_TIMESTAMP || lit == F_NOW || lit == F_TODAY
+}
+
 func (p *Parser) curTokenIs(t TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -440,6 +1952,13 @@ func (p *Parser) peekTokenIs(t TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// peekSecondTokenIs reports whether the token after the peek token is t,
+// lexing it from a copy of the lexer.
+func (p *Parser) peekSecondTokenIs(t TokenType) bool {
+	l := *p.l
+	return l.NextToken().Type == t
+}
+
 func (p *Parser) expectPeek(t TokenType) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
@@ -450,24 +1969,85 @@ func (p *Parser) expectPeek(t TokenType) bool {
 	}
 }
 
+func (p *Parser) curTokenIsName() bool {
+	return isName(p.curToken)
+}
+
+func (p *Parser) peekTokenIsName() bool {
+	return isName(p.peekToken)
+}
+
+// expectPeekName advances to the next token when it can name a field or
+// collection, including a keyword such as START.
+func (p *Parser) expectPeekName() bool {
+	if p.peekTokenIsName() {
+		p.nextToken()
+		return true
+	} else {
+		p.peekError(IDENT)
+		return false
+	}
+}
+
+// curTokenIsWord reports whether the current token is the given word
+// that is matched by literal rather than as a keyword, such as TO.
+func (p *Parser) curTokenIsWord(word string) bool {
+	return p.curTokenIs(IDENT) && strings.EqualFold(p.curToken.Literal, word)
+}
+
+func (p *Parser) peekTokenIsWord(word string) bool {
+	return p.peekTokenIs(IDENT) && strings.EqualFold(p.peekToken.Literal, word)
+}
+
+func (p *Parser) expectPeekWord(word string) bool {
+	if p.peekTokenIsWord(word) {
+		p.nextToken()
+		return true
+	} else {
+		p.peekError(TokenType(word))
+		return false
+	}
+}
+
 func (p *Parser) peekError(t TokenType) {
 	msg := fmt.Sprintf("expected next token tobe %s, got %s instead", t, p.peekToken.Type)
 	p.errors = append(p.errors, msg)
 }
 
-func parseTime(timeStr string) (time.Time, error) {
-	layouts := []string{
-		time.RFC3339,
-		"2006-01-02T15:04:05",
-		"2006-01-02",
-	}
-	for _, layout := range layouts {
-		t, err := time.Parse(layout, timeStr)
-		if err == nil {
-			return t, nil
+// parseFieldPath splits a field reference such as a.b or `a.b`.c into
+// its segments. Backtick-quoted segments may contain any character;
+// use \` and \\ to escape a backtick and a backslash.
+func parseFieldPath(s string) (firestore.FieldPath, error) {
+	var path firestore.FieldPath
+	var segment strings.Builder
+	quoted := false
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case quoted && ch == '\\' && i+1 < len(runes):
+			i++
+			segment.WriteRune(runes[i])
+		case ch == '`':
+			quoted = !quoted
+		case ch == '.' && !quoted:
+			if segment.Len() == 0 {
+				return nil, fmt.Errorf("invalid field path: %s", s)
+			}
+			path = append(path, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteRune(ch)
 		}
 	}
-	return time.Time{}, fmt.Errorf("invalid timestamp format: %s", timeStr)
+	if quoted {
+		return nil, fmt.Errorf("invalid field path: unterminated backtick in %s", s)
+	}
+	if segment.Len() == 0 {
+		return nil, fmt.Errorf("invalid field path: %s", s)
+	}
+	return append(path, segment.String()), nil
 }
 
 func validateCollectionGroupName(name string) error {
@@ -6,6 +6,7 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 func TestParse(t *testing.T) {
@@ -40,6 +41,35 @@ func TestParse(t *testing.T) {
 				NewFloatFilter("age", OPERATOR_EQ, 20.5),
 			}},
 		},
+		{
+			desc:  "query with negative int",
+			input: `QUERY user WHERE age > -5`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewIntFilter("age", OPERATOR_GT, -5),
+			}},
+		},
+		{
+			desc:  "query with int64",
+			input: `QUERY user WHERE id = 9223372036854775807`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewIntFilter("id", OPERATOR_EQ, 9223372036854775807),
+			}},
+		},
+		{
+			desc:  "query with scientific notation",
+			input: `QUERY user WHERE score < -3.5E-2 AND views >= 1e9`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewFloatFilter("score", OPERATOR_LT, -0.035),
+				NewFloatFilter("views", OPERATOR_GTE, 1e9),
+			}},
+		},
+		{
+			desc:  "query with negative values in array",
+			input: `QUERY user WHERE age IN [-1, -2.5]`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("age", OPERATOR_IN, []any{int64(-1), -2.5}),
+			}},
+		},
 		{
 			desc:  "query with not equal",
 			input: `QUERY user WHERE age != 20`,
@@ -80,14 +110,46 @@ func TestParse(t *testing.T) {
 			desc:  "query with IN",
 			input: `QUERY user WHERE age IN [20, 21, 22]`,
 			want: &QueryOperation{collection: "user", filters: []Filter{
-				NewArrayFilter("age", OPERATOR_IN, []any{20, 21, 22}),
+				NewArrayFilter("age", OPERATOR_IN, []any{int64(20), int64(21), int64(22)}),
 			}},
 		},
 		{
 			desc:  "query with IN by mutiple types",
 			input: `QUERY user WHERE age IN [20, 21.5, "22"]`,
 			want: &QueryOperation{collection: "user", filters: []Filter{
-				NewArrayFilter("age", OPERATOR_IN, []any{20, 21.5, "22"}),
+				NewArrayFilter("age", OPERATOR_IN, []any{int64(20), 21.5, "22"}),
+			}},
+		},
+		{
+			desc:  "query with NOT_IN",
+			input: `QUERY user WHERE age NOT_IN [20, 21]`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("age", OPERATOR_NOT_IN, []any{int64(20), int64(21)}),
+			}},
+		},
+		{
+			desc:  "query with NOT IN",
+			input: `QUERY user WHERE age NOT IN [20, 21]`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("age", OPERATOR_NOT_IN, []any{int64(20), int64(21)}),
+			}},
+		},
+		{
+			desc:  "query with IN followed by another filter",
+			input: `QUERY user WHERE age IN [20, 21] AND name = "John Doe"`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("age", OPERATOR_IN, []any{int64(20), int64(21)}),
+				NewStringFilter("name", OPERATOR_EQ, "John Doe"),
+			}},
+		},
+		{
+			desc:  "query with IN by timestamps",
+			input: `QUERY user WHERE created_at IN [TIMESTAMP("2006-01-02"), TIMESTAMP("2006-01-03")]`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("created_at", OPERATOR_IN, []any{
+					time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+					time.Date(2006, 1, 3, 0, 0, 0, 0, time.UTC),
+				}),
 			}},
 		},
 		{
@@ -128,6 +190,53 @@ func TestParse(t *testing.T) {
 				}()),
 			}},
 		},
+		{
+			desc:  "query with bool",
+			input: `QUERY user WHERE active = true AND deleted != FALSE`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewBoolFilter("active", OPERATOR_EQ, true),
+				NewBoolFilter("deleted", OPERATOR_NOT_EQ, false),
+			}},
+		},
+		{
+			desc:  "query with null",
+			input: `QUERY user WHERE deletedAt = null`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewNullFilter("deletedAt", OPERATOR_EQ),
+			}},
+		},
+		{
+			desc:  "query with nan",
+			input: `QUERY user WHERE score = NaN`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewNaNFilter("score", OPERATOR_EQ),
+			}},
+		},
+		{
+			desc:  "query with is null",
+			input: `QUERY user WHERE deletedAt IS NULL AND name IS NOT NULL`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewNullFilter("deletedAt", OPERATOR_EQ),
+				NewNullFilter("name", OPERATOR_NOT_EQ),
+			}},
+		},
+		{
+			desc:  "query with is nan",
+			input: `QUERY user WHERE score IS NAN OR rate IS NOT NAN`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewNaNFilter("score", OPERATOR_EQ),
+					NewNaNFilter("rate", OPERATOR_NOT_EQ),
+				}),
+			}},
+		},
+		{
+			desc:  "query with IN by bool and null",
+			input: `QUERY user WHERE flag IN [true, false, null]`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("flag", OPERATOR_IN, []any{true, false, nil}),
+			}},
+		},
 		{
 			desc:  "query with multiple filters",
 			input: `QUERY user WHERE age = 20 AND name = "John Doe"`,
@@ -136,6 +245,61 @@ func TestParse(t *testing.T) {
 				NewStringFilter("name", OPERATOR_EQ, "John Doe"),
 			}},
 		},
+		{
+			desc:  "query with or",
+			input: `QUERY user WHERE age = 20 OR name = "John Doe"`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewIntFilter("age", OPERATOR_EQ, 20),
+					NewStringFilter("name", OPERATOR_EQ, "John Doe"),
+				}),
+			}},
+		},
+		{
+			desc:  "query with and binds tighter than or",
+			input: `QUERY user WHERE age = 20 AND name = "John Doe" OR age = 30`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewAndFilter([]Filter{
+						NewIntFilter("age", OPERATOR_EQ, 20),
+						NewStringFilter("name", OPERATOR_EQ, "John Doe"),
+					}),
+					NewIntFilter("age", OPERATOR_EQ, 30),
+				}),
+			}},
+		},
+		{
+			desc:  "query with parenthesized or",
+			input: `QUERY user WHERE (status = "open" OR status = "pending") AND owner = "x" ORDER BY age`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewStringFilter("status", OPERATOR_EQ, "open"),
+					NewStringFilter("status", OPERATOR_EQ, "pending"),
+				}),
+				NewStringFilter("owner", OPERATOR_EQ, "x"),
+			}, orderBys: []OrderBy{{"age", firestore.Asc}}},
+		},
+		{
+			desc:  "query with nested parentheses",
+			input: `QUERY user WHERE ((age = 20))`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewIntFilter("age", OPERATOR_EQ, 20),
+			}},
+		},
+		{
+			desc:  "query with quoted field names",
+			input: "QUERY user SELECT `display name`, `a.b`.c WHERE `display name` = \"x\" ORDER BY `a.b`.c DESC",
+			want: &QueryOperation{collection: "user", selects: []string{"`display name`", "`a.b`.c"}, filters: []Filter{
+				NewStringFilter("`display name`", OPERATOR_EQ, "x"),
+			}, orderBys: []OrderBy{{"`a.b`.c", firestore.Desc}}},
+		},
+		{
+			desc:  "query with nested field paths",
+			input: `QUERY user SELECT address.city WHERE address.zip = "100" ORDER BY address.city`,
+			want: &QueryOperation{collection: "user", selects: []string{"address.city"}, filters: []Filter{
+				NewStringFilter("address.zip", OPERATOR_EQ, "100"),
+			}, orderBys: []OrderBy{{"address.city", firestore.Asc}}},
+		},
 		{
 			desc:  "query with trim head slash",
 			input: `QUERY /user`,
@@ -168,6 +332,11 @@ func TestParse(t *testing.T) {
 			input: `QUERY user ORDER BY age ASC, name DESC`,
 			want:  &QueryOperation{collection: "user", orderBys: []OrderBy{{"age", firestore.Asc}, {"name", firestore.Desc}}},
 		},
+		{
+			desc:  "query with multiple order by without direction",
+			input: `QUERY user ORDER BY age, name`,
+			want:  &QueryOperation{collection: "user", orderBys: []OrderBy{{"age", firestore.Asc}, {"name", firestore.Asc}}},
+		},
 		{
 			desc:  "query with order by and select and where",
 			input: `QUERY user SELECT name, age WHERE age = 20 ORDER BY age ASC`,
@@ -194,6 +363,46 @@ func TestParse(t *testing.T) {
 				NewIntFilter("age", OPERATOR_EQ, 20),
 			}, orderBys: []OrderBy{{"age", firestore.Desc}}, limit: 10},
 		},
+		{
+			desc:  "query with start at",
+			input: `QUERY user ORDER BY age START AT 20 LIMIT 10`,
+			want: &QueryOperation{collection: "user", orderBys: []OrderBy{{"age", firestore.Asc}},
+				startCursor: NewValuesCursor(true, []any{int64(20)}), limit: 10},
+		},
+		{
+			desc:  "query with start after and end before",
+			input: `QUERY user ORDER BY age, name START AFTER 20, "John" END BEFORE 30`,
+			want: &QueryOperation{collection: "user", orderBys: []OrderBy{{"age", firestore.Asc}, {"name", firestore.Asc}},
+				startCursor: NewValuesCursor(false, []any{int64(20), "John"}),
+				endCursor:   NewValuesCursor(false, []any{int64(30)})},
+		},
+		{
+			desc:  "query with end at timestamp",
+			input: `QUERY user ORDER BY created_at DESC END AT TIMESTAMP("2006-01-02")`,
+			want: &QueryOperation{collection: "user", orderBys: []OrderBy{{"created_at", firestore.Desc}},
+				endCursor: NewValuesCursor(true, []any{time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)})},
+		},
+		{
+			desc:  "query with document cursor",
+			input: `QUERY user WHERE age > 20 START AFTER /user/abc`,
+			want: &QueryOperation{collection: "user", filters: []Filter{NewIntFilter("age", OPERATOR_GT, 20)},
+				startCursor: NewDocumentCursor(false, "user/abc")},
+		},
+		{
+			desc:  "query with offset",
+			input: `QUERY user OFFSET 20`,
+			want:  &QueryOperation{collection: "user", offset: 20},
+		},
+		{
+			desc:  "query with limit and offset",
+			input: `QUERY user ORDER BY age LIMIT 10 OFFSET 20`,
+			want:  &QueryOperation{collection: "user", orderBys: []OrderBy{{"age", firestore.Asc}}, limit: 10, offset: 20},
+		},
+		{
+			desc:  "query with limit last",
+			input: `QUERY user ORDER BY age DESC LIMIT LAST 5`,
+			want:  &QueryOperation{collection: "user", orderBys: []OrderBy{{"age", firestore.Desc}}, limit: 5, limitToLast: true},
+		},
 		{
 			desc:  "query with __id__",
 			input: `QUERY user WHERE __id__ = "abc"`,
@@ -208,6 +417,13 @@ func TestParse(t *testing.T) {
 				NewArrayFilter("__id__", OPERATOR_IN, []any{"abc", "def"}),
 			}},
 		},
+		{
+			desc:  "query with __id__ NOT_IN",
+			input: `QUERY user WHERE __id__ NOT_IN ["abc", "def"]`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewArrayFilter("__id__", OPERATOR_NOT_IN, []any{"abc", "def"}),
+			}},
+		},
 		{
 			desc:  "count",
 			input: `COUNT user WHERE age = 20`,
@@ -222,16 +438,468 @@ func TestParse(t *testing.T) {
 				NewStringFilter("title", OPERATOR_EQ, "post-1-1"),
 			}},
 		},
+		{
+			desc:  "query with group by",
+			input: `QUERY orders SELECT status, COUNT(*), SUM(amount) AS total WHERE amount > 0 GROUP BY status ORDER BY status`,
+			want: &QueryOperation{
+				collection: "orders",
+				selects:    []string{"status"},
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_COUNT, "", "COUNT(*)"),
+					NewAggregate(AGGREGATE_SUM, "amount", "total"),
+				},
+				filters: []Filter{
+					NewIntFilter("amount", OPERATOR_GT, 0),
+				},
+				groupBys: []string{"status"},
+				orderBys: []OrderBy{{"status", firestore.Asc}},
+			},
+		},
+		{
+			desc:  "query with group by multiple fields",
+			input: `QUERY orders SELECT MIN(amount), MAX(amount) GROUP BY status, address.city LIMIT 100`,
+			want: &QueryOperation{
+				collection: "orders",
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_MIN, "amount", "MIN(amount)"),
+					NewAggregate(AGGREGATE_MAX, "amount", "MAX(amount)"),
+				},
+				groupBys: []string{"status", "address.city"},
+				limit:    100,
+			},
+		},
+		{
+			desc:  "query as of",
+			input: `QUERY user WHERE age = 20 LIMIT 10 AS OF TIMESTAMP("2024-01-02T10:03:00Z")`,
+			want: &QueryOperation{collection: "user", filters: []Filter{
+				NewIntFilter("age", OPERATOR_EQ, 20),
+			}, limit: 10, asOf: time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC)},
+		},
+		{
+			desc:  "get as of",
+			input: `GET user/1 SELECT name AS OF TIMESTAMP("2024-01-02T10:03:00Z")`,
+			want:  &GetOperation{collection: "user", docId: "1", selects: []string{"name"}, asOf: time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC)},
+		},
+		{
+			desc:  "count as of",
+			input: `COUNT user AS OF TIMESTAMP("2024-01-02")`,
+			want:  &CountOperation{collection: "user", asOf: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			desc:  "set as of",
+			input: `\asof TIMESTAMP("2024-01-02T10:03:00Z")`,
+			want:  &MetacommandAsOf{asOf: time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC)},
+		},
+		{
+			desc:  "reset as of",
+			input: `\asof off`,
+			want:  &MetacommandAsOf{},
+		},
+		{
+			desc:  "aggregate",
+			input: `AGGREGATE orders COUNT(*), SUM(amount) AS total, AVG(score) WHERE status = "paid"`,
+			want: &AggregateOperation{
+				collection: "orders",
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_COUNT, "", "COUNT(*)"),
+					NewAggregate(AGGREGATE_SUM, "amount", "total"),
+					NewAggregate(AGGREGATE_AVG, "score", "AVG(score)"),
+				},
+				filters: []Filter{
+					NewStringFilter("status", OPERATOR_EQ, "paid"),
+				},
+			},
+		},
+		{
+			desc:  "aggregate with collection group",
+			input: "AGGREGATE COLLECTION_GROUP posts COUNT(*) AS count, SUM(`stats.likes`) AS \"total likes\"",
+			want: &AggregateOperation{
+				collection:      "posts",
+				collectionGroup: true,
+				aggregates: []Aggregate{
+					NewAggregate(AGGREGATE_COUNT, "", "count"),
+					NewAggregate(AGGREGATE_SUM, "`stats.likes`", "total likes"),
+				},
+			},
+		},
+		{
+			desc:  "query with find nearest",
+			input: `QUERY docs WHERE lang = "en" FIND NEAREST embedding TO VECTOR([0.1, -2, 3.5]) DISTANCE COSINE LIMIT 5`,
+			want: &QueryOperation{
+				collection: "docs",
+				filters: []Filter{
+					NewStringFilter("lang", OPERATOR_EQ, "en"),
+				},
+				findNearest: NewFindNearest("embedding", firestore.Vector64{0.1, -2, 3.5}, firestore.DistanceMeasureCosine, 5, ""),
+			},
+		},
+		{
+			desc:  "query with find nearest and distance field",
+			input: `QUERY docs SELECT title FIND NEAREST meta.embedding TO VECTOR([1, 2]) DISTANCE DOT_PRODUCT LIMIT 10 DISTANCE FIELD score AS OF TIMESTAMP("2024-01-02")`,
+			want: &QueryOperation{
+				collection:  "docs",
+				selects:     []string{"title"},
+				findNearest: NewFindNearest("meta.embedding", firestore.Vector64{1, 2}, firestore.DistanceMeasureDotProduct, 10, "score"),
+				asOf:        time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			desc:  "query with keywords as fields",
+			input: `QUERY users SELECT end, order WHERE status = "a" ORDER BY start DESC`,
+			want: &QueryOperation{
+				collection: "users",
+				selects:    []string{"end", "order"},
+				filters: []Filter{
+					NewStringFilter("status", OPERATOR_EQ, "a"),
+				},
+				orderBys: []OrderBy{{"start", firestore.Desc}},
+			},
+		},
+		{
+			desc:  "query keyword collection",
+			input: `QUERY limit GROUP BY group`,
+			want:  &QueryOperation{collection: "limit", groupBys: []string{"group"}},
+		},
+		{
+			desc:  "query with write keywords as names",
+			input: `QUERY values SELECT into, set`,
+			want:  &QueryOperation{collection: "values", selects: []string{"into", "set"}},
+		},
+		{
+			desc:  "query with from as field",
+			input: `QUERY users SELECT from, to`,
+			want:  &QueryOperation{collection: "users", selects: []string{"from", "to"}},
+		},
+		{
+			desc:  "query with find nearest words as fields",
+			input: `QUERY docs WHERE to = "a" ORDER BY field`,
+			want: &QueryOperation{
+				collection: "docs",
+				filters: []Filter{
+					NewStringFilter("to", OPERATOR_EQ, "a"),
+				},
+				orderBys: []OrderBy{{"field", firestore.Asc}},
+			},
+		},
+		{
+			desc:  "query with vector filter",
+			input: `QUERY docs WHERE embedding = VECTOR([1.5, 2])`,
+			want: &QueryOperation{collection: "docs", filters: []Filter{
+				NewVectorFilter("embedding", OPERATOR_EQ, firestore.Vector64{1.5, 2}),
+			}},
+		},
+		{
+			desc:  "query with ref",
+			input: `QUERY posts WHERE author = REF("/users/abc")`,
+			want: &QueryOperation{collection: "posts", filters: []Filter{
+				NewRefFilter("author", OPERATOR_EQ, NewRef("users/abc")),
+			}},
+		},
+		{
+			desc:  "query with geopoint and bytes",
+			input: `QUERY shops WHERE location = GEOPOINT(35.6, -139) AND hash = BYTES("AQID")`,
+			want: &QueryOperation{collection: "shops", filters: []Filter{
+				NewGeoPointFilter("location", OPERATOR_EQ, &latlng.LatLng{Latitude: 35.6, Longitude: -139}),
+				NewBytesFilter("hash", OPERATOR_EQ, []byte{1, 2, 3}),
+			}},
+		},
+		{
+			desc:  "query with refs in array",
+			input: `QUERY posts WHERE author IN [REF("users/a"), REF("users/b")]`,
+			want: &QueryOperation{collection: "posts", filters: []Filter{
+				NewArrayFilter("author", OPERATOR_IN, []any{NewRef("users/a"), NewRef("users/b")}),
+			}},
+		},
+		{
+			desc:  "query with ref cursor",
+			input: `QUERY posts ORDER BY author START AT REF("users/a")`,
+			want: &QueryOperation{
+				collection:  "posts",
+				orderBys:    []OrderBy{{"author", firestore.Asc}},
+				startCursor: NewValuesCursor(true, []any{NewRef("users/a")}),
+			},
+		},
+		{
+			desc:  "explain query",
+			input: `EXPLAIN QUERY user WHERE age = 20`,
+			want: &ExplainOperation{query: &QueryOperation{collection: "user", filters: []Filter{
+				NewIntFilter("age", OPERATOR_EQ, 20),
+			}}},
+		},
+		{
+			desc:  "explain analyze query",
+			input: `EXPLAIN ANALYZE QUERY user ORDER BY age DESC LIMIT 10`,
+			want: &ExplainOperation{analyze: true, query: &QueryOperation{
+				collection: "user",
+				orderBys:   []OrderBy{{"age", firestore.Desc}},
+				limit:      10,
+			}},
+		},
+		{
+			desc:  "insert with id",
+			input: `INSERT INTO user ID "abc" VALUES {name: "takashi", age: 20}`,
+			want: &InsertOperation{collection: "user", docId: "abc", data: map[string]any{
+				"name": "takashi",
+				"age":  int64(20),
+			}},
+		},
+		{
+			desc:  "insert with id in lower case",
+			input: `INSERT INTO values id "abc" VALUES {set: 1}`,
+			want:  &InsertOperation{collection: "values", docId: "abc", data: map[string]any{"set": int64(1)}},
+		},
+		{
+			desc:  "insert with auto id",
+			input: `INSERT INTO user/1/posts VALUES {"title": "hello", tags: ["a", "b"], draft: false,}`,
+			want: &InsertOperation{collection: "user/1/posts", data: map[string]any{
+				"title": "hello",
+				"tags":  []any{"a", "b"},
+				"draft": false,
+			}},
+		},
+		{
+			desc:  "insert with nested object and literals",
+			input: "INSERT INTO posts VALUES {author: REF(\"users/a\"), createdAt: TIMESTAMP(\"2006-01-02\"), meta: {score: 1.5, note: NULL}, `order`: 1}",
+			want: &InsertOperation{collection: "posts", data: map[string]any{
+				"author":    NewRef("users/a"),
+				"createdAt": time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+				"meta":      map[string]any{"score": 1.5, "note": nil},
+				"order":     int64(1),
+			}},
+		},
+		{
+			desc:  "insert with keyword key",
+			input: `INSERT INTO user VALUES {limit: 10}`,
+			want:  &InsertOperation{collection: "user", data: map[string]any{"limit": int64(10)}},
+		},
+		{
+			desc:  "set",
+			input: `SET user/1 {name: "takashi"}`,
+			want:  &SetOperation{collection: "user", docId: "1", data: map[string]any{"name": "takashi"}},
+		},
+		{
+			desc:  "set merge",
+			input: `SET user/1/posts/2 {address: {city: "tokyo"}} MERGE`,
+			want: &SetOperation{collection: "user/1/posts", docId: "2", merge: true, data: map[string]any{
+				"address": map[string]any{"city": "tokyo"},
+			}},
+		},
+		{
+			desc:  "update",
+			input: "UPDATE user/1 SET name = \"takashi\", address.city = \"tokyo\", `a.b` = NULL, createdAt = TIMESTAMP(\"2006-01-02\")",
+			want: &UpdateOperation{collection: "user", docId: "1", updates: []Update{
+				NewUpdate("name", "takashi"),
+				NewUpdate("address.city", "tokyo"),
+				NewUpdate("`a.b`", nil),
+				NewUpdate("createdAt", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)),
+			}},
+		},
+		{
+			desc:  "update with transforms",
+			input: `UPDATE user/1 SET n = INCREMENT(1), score = INCREMENT(-0.5), tags = ARRAY_UNION("x", "y"), friends = ARRAY_REMOVE(REF("user/2")), old = DELETE_FIELD(), updatedAt = SERVER_TIMESTAMP()`,
+			want: &UpdateOperation{collection: "user", docId: "1", updates: []Update{
+				NewUpdate("n", NewTransform(TRANSFORM_INCREMENT, []any{int64(1)})),
+				NewUpdate("score", NewTransform(TRANSFORM_INCREMENT, []any{-0.5})),
+				NewUpdate("tags", NewTransform(TRANSFORM_ARRAY_UNION, []any{"x", "y"})),
+				NewUpdate("friends", NewTransform(TRANSFORM_ARRAY_REMOVE, []any{NewRef("user/2")})),
+				NewUpdate("old", NewTransform(TRANSFORM_DELETE_FIELD, nil)),
+				NewUpdate("updatedAt", NewTransform(TRANSFORM_SERVER_TIMESTAMP, nil)),
+			}},
+		},
+		{
+			desc:  "update with object and array values",
+			input: `UPDATE user/1/posts/2 SET meta = {likes: 0}, tags = ["a"]`,
+			want: &UpdateOperation{collection: "user/1/posts", docId: "2", updates: []Update{
+				NewUpdate("meta", map[string]any{"likes": int64(0)}),
+				NewUpdate("tags", []any{"a"}),
+			}},
+		},
+		{
+			desc:  "bulk update",
+			input: `UPDATE user SET status = "archived", n = INCREMENT(1) WHERE lastLogin < TIMESTAMP("2006-01-02") AND age > 20 LIMIT 100`,
+			want: &UpdateOperation{
+				collection: "user",
+				updates: []Update{
+					NewUpdate("status", "archived"),
+					NewUpdate("n", NewTransform(TRANSFORM_INCREMENT, []any{int64(1)})),
+				},
+				query: &QueryOperation{collection: "user", limit: 100, filters: []Filter{
+					NewTimestampFilter("lastLogin", OPERATOR_LT, time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)),
+					NewIntFilter("age", OPERATOR_GT, 20),
+				}},
+			},
+		},
+		{
+			desc:  "bulk update with keywords as names",
+			input: `UPDATE values SET set = 1, merge = 2`,
+			want: &UpdateOperation{
+				collection: "values",
+				updates:    []Update{NewUpdate("set", int64(1)), NewUpdate("merge", int64(2))},
+				query:      &QueryOperation{collection: "values"},
+			},
+		},
+		{
+			desc:  "bulk update without where",
+			input: `UPDATE user/1/posts SET draft = false`,
+			want: &UpdateOperation{
+				collection: "user/1/posts",
+				updates:    []Update{NewUpdate("draft", false)},
+				query:      &QueryOperation{collection: "user/1/posts"},
+			},
+		},
+		{
+			desc:  "delete from",
+			input: `DELETE FROM user WHERE status = "archived" OR age IS NULL`,
+			want: &DeleteOperation{collection: "user", query: &QueryOperation{collection: "user", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewStringFilter("status", OPERATOR_EQ, "archived"),
+					NewNullFilter("age", OPERATOR_EQ),
+				}),
+			}}},
+		},
+		{
+			desc:  "delete from with limit",
+			input: `DELETE FROM user/1/posts LIMIT 10`,
+			want:  &DeleteOperation{collection: "user/1/posts", query: &QueryOperation{collection: "user/1/posts", limit: 10}},
+		},
+		{
+			desc:  "delete from keyword collection",
+			input: `DELETE FROM group WHERE from = "a"`,
+			want: &DeleteOperation{collection: "group", query: &QueryOperation{collection: "group", filters: []Filter{
+				NewStringFilter("from", OPERATOR_EQ, "a"),
+			}}},
+		},
+		{
+			desc:  "delete document",
+			input: `DELETE user/1`,
+			want:  &DeleteOperation{collection: "user", docId: "1"},
+		},
+		{
+			desc:  "delete document recursive",
+			input: `DELETE /user/1/posts/2 RECURSIVE`,
+			want:  &DeleteOperation{collection: "user/1/posts", docId: "2", recursive: true},
+		},
+		{
+			desc:  "set if not exists",
+			input: `SET user/1 {name: "takashi"} IF NOT EXISTS`,
+			want: &SetOperation{collection: "user", docId: "1", data: map[string]any{"name": "takashi"},
+				precondition: NewPrecondition(PRECONDITION_NOT_EXISTS, time.Time{})},
+		},
+		{
+			desc:  "set merge if updated at",
+			input: `SET user/1 {name: "takashi"} MERGE IF UPDATED_AT = TIMESTAMP("2024-01-02T10:03:00.123456Z")`,
+			want: &SetOperation{collection: "user", docId: "1", data: map[string]any{"name": "takashi"}, merge: true,
+				precondition: NewPrecondition(PRECONDITION_UPDATED_AT, time.Date(2024, 1, 2, 10, 3, 0, 123456000, time.UTC))},
+		},
+		{
+			desc:  "update if exists",
+			input: `UPDATE user/1 SET name = "takashi" IF EXISTS`,
+			want: &UpdateOperation{collection: "user", docId: "1", updates: []Update{NewUpdate("name", "takashi")},
+				precondition: NewPrecondition(PRECONDITION_EXISTS, time.Time{})},
+		},
+		{
+			desc:  "update if updated at in lower case",
+			input: `UPDATE user/1 SET updated_at = SERVER_TIMESTAMP() if updated_at = TIMESTAMP("2024-01-02 10:03:00")`,
+			want: &UpdateOperation{collection: "user", docId: "1",
+				updates:      []Update{NewUpdate("updated_at", NewTransform(TRANSFORM_SERVER_TIMESTAMP, nil))},
+				precondition: NewPrecondition(PRECONDITION_UPDATED_AT, time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC))},
+		},
+		{
+			desc:  "delete document if exists",
+			input: `DELETE user/1 IF EXISTS`,
+			want:  &DeleteOperation{collection: "user", docId: "1", precondition: NewPrecondition(PRECONDITION_EXISTS, time.Time{})},
+		},
+		{
+			desc:  "copy document",
+			input: `COPY user/1 TO /archive/2024/user/1`,
+			want:  &CopyOperation{collection: "user", docId: "1", toCollection: "archive/2024/user", toDocId: "1"},
+		},
+		{
+			desc:  "copy document recursive",
+			input: `COPY user/1 TO user/2 RECURSIVE`,
+			want:  &CopyOperation{collection: "user", docId: "1", toCollection: "user", toDocId: "2", recursive: true},
+		},
+		{
+			desc:  "copy document into its subcollection",
+			input: `COPY user/1 TO user/1/backup/1`,
+			want:  &CopyOperation{collection: "user", docId: "1", toCollection: "user/1/backup", toDocId: "1"},
+		},
+		{
+			desc:  "copy collection",
+			input: `COPY COLLECTION user TO archive/2024/user`,
+			want:  &CopyOperation{collection: "user", toCollection: "archive/2024/user", query: &QueryOperation{collection: "user"}},
+		},
+		{
+			desc:  "copy keyword collection",
+			input: `COPY COLLECTION values TO order`,
+			want:  &CopyOperation{collection: "values", toCollection: "order", query: &QueryOperation{collection: "values"}},
+		},
+		{
+			desc:  "copy collection with where and recursive",
+			input: `COPY COLLECTION user TO archived_user WHERE status = "archived" LIMIT 100 RECURSIVE`,
+			want: &CopyOperation{collection: "user", toCollection: "archived_user", recursive: true, query: &QueryOperation{
+				collection: "user",
+				limit:      100,
+				filters:    []Filter{NewStringFilter("status", OPERATOR_EQ, "archived")},
+			}},
+		},
+		{
+			desc:  "drop collection",
+			input: `DROP COLLECTION user/1/posts`,
+			want:  &DropCollectionOperation{collection: "user/1/posts"},
+		},
+		{
+			desc:  "drop keyword collection",
+			input: `DROP COLLECTION order`,
+			want:  &DropCollectionOperation{collection: "order"},
+		},
+		{
+			desc:  "begin",
+			input: `BEGIN`,
+			want:  &TransactionOperation{operationType: OPERATION_TYPE_BEGIN},
+		},
+		{
+			desc:  "commit",
+			input: `commit`,
+			want:  &TransactionOperation{operationType: OPERATION_TYPE_COMMIT},
+		},
+		{
+			desc:  "rollback",
+			input: `ROLLBACK`,
+			want:  &TransactionOperation{operationType: OPERATION_TYPE_ROLLBACK},
+		},
 		{
 			desc:  "get",
 			input: `GET user/1`,
 			want:  &GetOperation{collection: "user", docId: "1"},
 		},
+		{
+			desc:  "get with unicode path",
+			input: `GET ユーザー/山田`,
+			want:  &GetOperation{collection: "ユーザー", docId: "山田"},
+		},
+		{
+			desc:  "query with unicode collection and field",
+			input: `QUERY ユーザー WHERE 名前 = "太郎"`,
+			want: &QueryOperation{collection: "ユーザー", filters: []Filter{
+				NewStringFilter("名前", OPERATOR_EQ, "太郎"),
+			}},
+		},
 		{
 			desc:  "get with select",
 			input: `GET user/1 SELECT name, age`,
 			want:  &GetOperation{collection: "user", docId: "1", selects: []string{"name", "age"}},
 		},
+		{
+			desc:  "get with nested select",
+			input: `GET user/1 SELECT address.city, name`,
+			want:  &GetOperation{collection: "user", docId: "1", selects: []string{"address.city", "name"}},
+		},
+		{
+			desc:  "get with select of find nearest words",
+			input: `GET users/abc SELECT distance, to`,
+			want:  &GetOperation{collection: "users", docId: "abc", selects: []string{"distance", "to"}},
+		},
 		{
 			desc:  "count with multiple filters",
 			input: `COUNT user WHERE age = 20 AND name = "John Doe"`,
@@ -240,6 +908,19 @@ func TestParse(t *testing.T) {
 				NewStringFilter("name", OPERATOR_EQ, "John Doe"),
 			}},
 		},
+		{
+			desc:  "count with or",
+			input: `COUNT user WHERE age = 20 OR (age = 30 AND name = "John Doe")`,
+			want: &CountOperation{collection: "user", filters: []Filter{
+				NewOrFilter([]Filter{
+					NewIntFilter("age", OPERATOR_EQ, 20),
+					NewAndFilter([]Filter{
+						NewIntFilter("age", OPERATOR_EQ, 30),
+						NewStringFilter("name", OPERATOR_EQ, "John Doe"),
+					}),
+				}),
+			}},
+		},
 		{
 			desc:  "list collections",
 			input: `\d`,
@@ -252,6 +933,21 @@ func TestParse(t *testing.T) {
 				baseDoc: "user/1",
 			},
 		},
+		{
+			desc:  "timezone",
+			input: `\timezone "+09:00"`,
+			want:  &MetacommandTimezone{location: time.FixedZone("+09:00", 9*60*60)},
+		},
+		{
+			desc:  "show timezone",
+			input: `\timezone`,
+			want:  &MetacommandTimezone{},
+		},
+		{
+			desc:  "as of with interval",
+			input: `QUERY user AS OF TIMESTAMP("2024-01-02") - INTERVAL '1 day'`,
+			want:  &QueryOperation{collection: "user", asOf: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
 		{
 			desc:  "pager on",
 			input: `\pager on`,
@@ -266,6 +962,16 @@ func TestParse(t *testing.T) {
 				on: false,
 			},
 		},
+		{
+			desc:  "dry run on",
+			input: `\dryrun on`,
+			want:  &MetacommandDryRun{on: true},
+		},
+		{
+			desc:  "dry run off",
+			input: `\dryrun off`,
+			want:  &MetacommandDryRun{on: false},
+		},
 	}
 
 	for _, tt := range tests {
@@ -281,3 +987,500 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimeExpression(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 10, 20, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		desc     string
+		input    string
+		location *time.Location
+		want     time.Time
+	}{
+		{
+			desc:     "now",
+			input:    `NOW()`,
+			location: time.UTC,
+			want:     now,
+		},
+		{
+			desc:     "now minus interval",
+			input:    `NOW() - INTERVAL '7 days'`,
+			location: time.UTC,
+			want:     time.Date(2024, 3, 3, 20, 30, 0, 0, time.UTC),
+		},
+		{
+			desc:     "now minus interval without spaces",
+			input:    `NOW()-INTERVAL '7 days'`,
+			location: time.UTC,
+			want:     time.Date(2024, 3, 3, 20, 30, 0, 0, time.UTC),
+		},
+		{
+			desc:     "today plus intervals",
+			input:    `TODAY() + INTERVAL "9 hours" - INTERVAL '1 day'`,
+			location: time.UTC,
+			want:     time.Date(2024, 3, 9, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "today in session location",
+			input:    `TODAY()`,
+			location: tokyo,
+			want:     time.Date(2024, 3, 11, 0, 0, 0, 0, tokyo),
+		},
+		{
+			desc:     "today with zone",
+			input:    `TODAY("UTC")`,
+			location: tokyo,
+			want:     time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "timestamp in session location",
+			input:    `TIMESTAMP("2024-01-01 09:00")`,
+			location: tokyo,
+			want:     time.Date(2024, 1, 1, 9, 0, 0, 0, tokyo),
+		},
+		{
+			desc:     "timestamp with zone",
+			input:    `TIMESTAMP("2024-01-01 09:00", "Asia/Tokyo")`,
+			location: time.UTC,
+			want:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "timestamp with offset",
+			input:    `TIMESTAMP("2024-01-01T09:00:00", "-05:00")`,
+			location: time.UTC,
+			want:     time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			l := NewLexer(`QUERY events WHERE at >= ` + tt.input)
+			p := NewParser(l)
+			p.SetLocation(tt.location)
+			p.now = func() time.Time { return now }
+			got, err := p.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value := got.(*QueryOperation).filters[0].Value().(time.Time)
+			assert.True(t, tt.want.Equal(value), "want %s, got %s", tt.want, value)
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		desc    string
+		input   string
+		wantErr string
+	}{
+		{
+			desc:  "unclosed parenthesis",
+			input: `QUERY user WHERE (age = 20 OR age = 30`,
+		},
+		{
+			desc:  "NOT_IN without array",
+			input: `QUERY user WHERE age NOT_IN 20`,
+		},
+		{
+			desc:  "IS without null or nan",
+			input: `QUERY user WHERE name IS "x"`,
+		},
+		{
+			desc:  "int out of int64 range",
+			input: `QUERY user WHERE id = 9223372036854775808`,
+		},
+		{
+			desc:  "unterminated backtick",
+			input: "QUERY user WHERE `name = 1",
+		},
+		{
+			desc:  "empty field path segment",
+			input: "QUERY user SELECT a..b",
+		},
+		{
+			desc:  "unterminated array",
+			input: `QUERY user WHERE age IN [20, 21`,
+		},
+		{
+			desc:  "cursor with more values than order by",
+			input: `QUERY user ORDER BY age START AT 20, "John"`,
+		},
+		{
+			desc:  "start with invalid keyword",
+			input: `QUERY user ORDER BY age START BEFORE 20`,
+		},
+		{
+			desc:  "limit last without order by",
+			input: `QUERY user LIMIT LAST 5`,
+		},
+		{
+			desc:  "negative limit",
+			input: `QUERY user LIMIT -1`,
+		},
+		{
+			desc:  "clause out of order",
+			input: `QUERY user LIMIT 10 ORDER BY age`,
+		},
+		{
+			desc:  "aggregate without aggregation",
+			input: `AGGREGATE orders WHERE status = "paid"`,
+		},
+		{
+			desc:  "aggregate count with field",
+			input: `AGGREGATE orders COUNT(amount)`,
+		},
+		{
+			desc:  "aggregate with duplicate alias",
+			input: `AGGREGATE orders SUM(amount) AS total, AVG(amount) AS total`,
+		},
+		{
+			desc:  "aggregate with min",
+			input: `AGGREGATE orders MIN(amount)`,
+		},
+		{
+			desc:  "query aggregation without group by",
+			input: `QUERY orders SELECT COUNT(*)`,
+		},
+		{
+			desc:  "query selected field not grouped",
+			input: `QUERY orders SELECT status, COUNT(*) GROUP BY address.city`,
+		},
+		{
+			desc:  "query alias same as selected field",
+			input: `QUERY orders SELECT status, COUNT(*) AS status GROUP BY status`,
+		},
+		{
+			desc:  "query group by after order by",
+			input: `QUERY orders ORDER BY status GROUP BY status`,
+		},
+		{
+			desc:  "as of without timestamp",
+			input: `GET user/1 AS OF "2024-01-02"`,
+		},
+		{
+			desc:  "as of before where",
+			input: `COUNT user AS OF TIMESTAMP("2024-01-02") WHERE age = 20`,
+		},
+		{
+			desc:    "aggregate as of",
+			input:   `AGGREGATE user COUNT(*) AS OF TIMESTAMP("2024-01-02")`,
+			wantErr: "invalid: AS OF is not supported with AGGREGATE",
+		},
+		{
+			desc:    "aggregation without group by as of",
+			input:   `QUERY user SELECT COUNT(*) AS OF TIMESTAMP("2024-01-02")`,
+			wantErr: "invalid: COUNT(*) requires GROUP BY",
+		},
+		{
+			desc:  "set as of without argument",
+			input: `\asof`,
+		},
+		{
+			desc:  "find nearest without limit",
+			input: `QUERY docs FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE`,
+		},
+		{
+			desc:  "find nearest with zero limit",
+			input: `QUERY docs FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE LIMIT 0`,
+		},
+		{
+			desc:  "find nearest with limit over max",
+			input: `QUERY docs FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE LIMIT 1001`,
+		},
+		{
+			desc:  "find nearest with unknown measure",
+			input: `QUERY docs FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE MANHATTAN LIMIT 5`,
+		},
+		{
+			desc:  "find nearest with array",
+			input: `QUERY docs FIND NEAREST embedding TO [1, 2] DISTANCE COSINE LIMIT 5`,
+		},
+		{
+			desc:  "find nearest with order by",
+			input: `QUERY docs FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE LIMIT 5 ORDER BY title`,
+		},
+		{
+			desc:  "find nearest with offset",
+			input: `QUERY docs FIND NEAREST embedding TO VECTOR([1, 2]) DISTANCE COSINE LIMIT 5 OFFSET 1`,
+		},
+		{
+			desc:  "empty vector",
+			input: `QUERY docs WHERE embedding = VECTOR([])`,
+		},
+		{
+			desc:  "vector with string",
+			input: `QUERY docs WHERE embedding = VECTOR([1, "a"])`,
+		},
+		{
+			desc:  "ref to collection",
+			input: `QUERY posts WHERE author = REF("users")`,
+		},
+		{
+			desc:  "ref without string",
+			input: `QUERY posts WHERE author = REF(users/abc)`,
+		},
+		{
+			desc:  "geopoint out of range",
+			input: `QUERY shops WHERE location = GEOPOINT(91, 0)`,
+		},
+		{
+			desc:  "geopoint with one number",
+			input: `QUERY shops WHERE location = GEOPOINT(35.6)`,
+		},
+		{
+			desc:  "invalid base64 bytes",
+			input: `QUERY shops WHERE hash = BYTES("!!")`,
+		},
+		{
+			desc:  "timestamp with unknown zone",
+			input: `QUERY events WHERE at > TIMESTAMP("2024-01-01", "Nowhere/City")`,
+		},
+		{
+			desc:  "interval without keyword",
+			input: `QUERY events WHERE at > NOW() - '7 days'`,
+		},
+		{
+			desc:  "interval with unknown unit",
+			input: `QUERY events WHERE at > NOW() - INTERVAL '7 fortnights'`,
+		},
+		{
+			desc:  "now with argument",
+			input: `QUERY events WHERE at > NOW("UTC")`,
+		},
+		{
+			desc:  "unknown timezone",
+			input: `\timezone Nowhere/City`,
+		},
+		{
+			desc:  "explain get",
+			input: `EXPLAIN GET user/1`,
+		},
+		{
+			desc:  "explain without operation",
+			input: `EXPLAIN ANALYZE`,
+		},
+		{
+			desc:  "insert without values",
+			input: `INSERT INTO user {name: "takashi"}`,
+		},
+		{
+			desc:  "insert into document path",
+			input: `INSERT INTO user/1 VALUES {name: "takashi"}`,
+		},
+		{
+			desc:  "insert with id containing slash",
+			input: `INSERT INTO user ID "a/b" VALUES {name: "takashi"}`,
+		},
+		{
+			desc:  "set collection path",
+			input: `SET user {name: "takashi"}`,
+		},
+		{
+			desc:  "object with duplicate key",
+			input: `SET user/1 {name: "a", name: "b"}`,
+		},
+		{
+			desc:  "object with dotted key",
+			input: `SET user/1 {address.city: "tokyo"}`,
+		},
+		{
+			desc:  "unclosed object",
+			input: `SET user/1 {name: "a"`,
+		},
+		{
+			desc:  "object without colon",
+			input: `SET user/1 {name "a"}`,
+		},
+		{
+			desc:  "update without set",
+			input: `UPDATE user/1 name = "a"`,
+		},
+		{
+			desc:  "bulk update with order by",
+			input: `UPDATE user SET name = "a" WHERE age > 20 ORDER BY age`,
+		},
+		{
+			desc:  "delete without from",
+			input: `DELETE user WHERE age > 20`,
+		},
+		{
+			desc:  "delete from document path",
+			input: `DELETE FROM user/1`,
+		},
+		{
+			desc:  "delete recursive with trailing token",
+			input: `DELETE user/1 RECURSIVE user/2`,
+		},
+		{
+			desc:  "drop without collection keyword",
+			input: `DROP user`,
+		},
+		{
+			desc:  "drop document path",
+			input: `DROP COLLECTION user/1`,
+		},
+		{
+			desc:  "begin with trailing token",
+			input: `BEGIN TRANSACTION`,
+		},
+		{
+			desc:  "copy without to",
+			input: `COPY user/1 user/2`,
+		},
+		{
+			desc:  "copy document to collection",
+			input: `COPY user/1 TO archive`,
+		},
+		{
+			desc:  "copy collection to document",
+			input: `COPY COLLECTION user TO archive/1`,
+		},
+		{
+			desc:  "copy onto itself",
+			input: `COPY /user/1 TO user/1`,
+		},
+		{
+			desc:  "copy recursive into itself",
+			input: `COPY COLLECTION user TO user/1/user RECURSIVE`,
+		},
+		{
+			desc:  "copy document with where",
+			input: `COPY user/1 TO user/2 WHERE age > 20`,
+		},
+		{
+			desc:  "update if not exists",
+			input: `UPDATE user/1 SET name = "a" IF NOT EXISTS`,
+		},
+		{
+			desc:  "delete if not exists",
+			input: `DELETE user/1 IF NOT EXISTS`,
+		},
+		{
+			desc:    "bulk update with precondition",
+			input:   `UPDATE user SET name = "a" IF EXISTS`,
+			wantErr: "invalid: IF can only be used when writing a single document",
+		},
+		{
+			desc:    "bulk delete with precondition",
+			input:   `DELETE FROM user WHERE age > 20 IF EXISTS`,
+			wantErr: "invalid: IF can only be used when writing a single document",
+		},
+		{
+			desc:    "recursive delete with precondition",
+			input:   `DELETE user/1 RECURSIVE IF EXISTS`,
+			wantErr: "invalid: IF cannot be combined with RECURSIVE",
+		},
+		{
+			desc:  "unknown precondition",
+			input: `SET user/1 {name: "a"} IF MISSING`,
+		},
+		{
+			desc:  "updated at without timestamp",
+			input: `SET user/1 {name: "a"} IF UPDATED_AT = "2024-01-02"`,
+		},
+		{
+			desc:  "delete with negative limit",
+			input: `DELETE FROM user LIMIT -1`,
+		},
+		{
+			desc:  "update duplicate field",
+			input: `UPDATE user/1 SET name = "a", name = "b"`,
+		},
+		{
+			desc:  "update document id",
+			input: `UPDATE user/1 SET __id__ = "a"`,
+		},
+		{
+			desc:  "increment without number",
+			input: `UPDATE user/1 SET n = INCREMENT("1")`,
+		},
+		{
+			desc:  "array union without values",
+			input: `UPDATE user/1 SET tags = ARRAY_UNION()`,
+		},
+		{
+			desc:  "delete field with argument",
+			input: `UPDATE user/1 SET old = DELETE_FIELD(1)`,
+		},
+		{
+			desc:  "update trailing comma",
+			input: `UPDATE user/1 SET name = "a",`,
+		},
+		{
+			desc:  "missing filter after or",
+			input: `QUERY user WHERE age = 20 OR`,
+		},
+		{
+			desc:  "dry run without on or off",
+			input: `\dryrun`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			l := NewLexer(tt.input)
+			p := NewParser(l)
+			_, err := p.Parse()
+			assert.Error(t, err)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		want  firestore.FieldPath
+	}{
+		{
+			desc:  "simple",
+			input: "name",
+			want:  firestore.FieldPath{"name"},
+		},
+		{
+			desc:  "dotted",
+			input: "address.city",
+			want:  firestore.FieldPath{"address", "city"},
+		},
+		{
+			desc:  "quoted",
+			input: "`display name`",
+			want:  firestore.FieldPath{"display name"},
+		},
+		{
+			desc:  "quoted with dot",
+			input: "`a.b`.c",
+			want:  firestore.FieldPath{"a.b", "c"},
+		},
+		{
+			desc:  "quoted with escape",
+			input: "`a\\`b`",
+			want:  firestore.FieldPath{"a`b"},
+		},
+		{
+			desc:  "non-ascii",
+			input: "`名前`",
+			want:  firestore.FieldPath{"名前"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := parseFieldPath(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"cloud.google.com/go/firestore"
 	"github.com/c-bata/go-prompt"
@@ -18,6 +23,7 @@ import (
 	"github.com/olekukonko/tablewriter/tw"
 	"github.com/shibukawa/configdir"
 	"golang.org/x/exp/slices"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 const LongLine = "--------------------------------------------------------------------------"
@@ -33,11 +39,11 @@ func deleteWordWithSlash(buf *prompt.Buffer) {
 	i := len(runes) - 1
 
 	// Skip trailing separators
-	for i >= 0 && (runes[i] == ' ' || runes[i] == '/') {
+	for i >= 0 && isWordSeparator(runes[i]) {
 		i--
 	}
 	// Delete until next separator
-	for i >= 0 && runes[i] != ' ' && runes[i] != '/' {
+	for i >= 0 && !isWordSeparator(runes[i]) {
 		i--
 	}
 
@@ -45,6 +51,10 @@ func deleteWordWithSlash(buf *prompt.Buffer) {
 	buf.DeleteBeforeCursor(count)
 }
 
+func isWordSeparator(ch rune) bool {
+	return ch == '/' || unicode.IsSpace(ch)
+}
+
 type OutputMode string
 
 const (
@@ -66,9 +76,22 @@ type Repl struct {
 	outputMode       OutputMode
 	exe              *Executor
 	enabledPager     bool
+	asOf             time.Time
+	location         *time.Location
+	readOnly         bool
+	dryRun           bool
+	confirmWrites    bool
+	tx               *Transaction
 	collectionsCache map[string][]string
 }
 
+var (
+	// ErrReadOnly is returned for writes in read-only mode.
+	ErrReadOnly = errors.New("read-only mode")
+	// ErrNoTransaction is returned for COMMIT and ROLLBACK without BEGIN.
+	ErrNoTransaction = errors.New("no transaction is open")
+)
+
 func NewRepl(ctx context.Context, fs *firestore.Client, in io.Reader, out io.Writer, outputMode OutputMode) *Repl {
 	return &Repl{
 		ctx:              ctx,
@@ -78,10 +101,22 @@ func NewRepl(ctx context.Context, fs *firestore.Client, in io.Reader, out io.Wri
 		outputMode:       outputMode,
 		exe:              NewExecutor(ctx, fs),
 		enabledPager:     false,
+		location:         time.UTC,
 		collectionsCache: map[string][]string{},
 	}
 }
 
+// SetReadOnly rejects every statement that changes data.
+func (r *Repl) SetReadOnly(readOnly bool) {
+	r.readOnly = readOnly
+}
+
+// SetDryRun shows what statements that change data would change instead of
+// writing.
+func (r *Repl) SetDryRun(dryRun bool) {
+	r.dryRun = dryRun
+}
+
 func (r *Repl) completer(d prompt.Document) []prompt.Suggest {
 	w := d.GetWordBeforeCursor()
 	if w == "" {
@@ -108,12 +143,14 @@ func (r *Repl) completer(d prompt.Document) []prompt.Suggest {
 }
 
 func (r *Repl) Start() {
+	r.confirmWrites = true
 	history := r.readHistory()
 
 	p := prompt.New(
 		r.promptProcessLine,
 		r.completer,
 		prompt.OptionPrefix("> "),
+		prompt.OptionLivePrefix(r.livePrefix),
 		prompt.OptionSwitchKeyBindMode(prompt.CommonKeyBind),
 		prompt.OptionAddASCIICodeBind(prompt.ASCIICodeBind{
 			ASCIICode: []byte{0x1b, 0x62}, // Alt/Option + Left
@@ -130,6 +167,26 @@ func (r *Repl) Start() {
 		prompt.OptionHistory(history),
 	)
 	p.Run()
+	r.closeTransaction()
+}
+
+// livePrefix shows dry-run mode, an open transaction and the session read
+// time set by \asof in the prompt.
+func (r *Repl) livePrefix() (string, bool) {
+	states := []string{}
+	if r.dryRun {
+		states = append(states, "dry run")
+	}
+	if r.tx != nil {
+		states = append(states, "transaction")
+	}
+	if !r.asOf.IsZero() {
+		states = append(states, fmt.Sprintf("as of %s", r.asOf.In(r.location).Format(time.RFC3339)))
+	}
+	if len(states) == 0 {
+		return "", false
+	}
+	return strings.Join(states, ", ") + "> ", true
 }
 
 func (r *Repl) promptProcessLine(line string) {
@@ -148,6 +205,7 @@ func (r *Repl) promptProcessLine(line string) {
 func (r *Repl) ProcessLine(line string) {
 	lexer := NewLexer(line)
 	parser := NewParser(lexer)
+	parser.SetLocation(r.location)
 	op, err := parser.Parse()
 	if err != nil {
 		fmt.Fprintf(r.out, "error: %s\n", err)
@@ -158,22 +216,80 @@ func (r *Repl) ProcessLine(line string) {
 	}
 
 	if err := r.executeOperation(op); err != nil {
+		// an expired transaction cannot be used again, so it is closed
+		if errors.Is(err, ErrTransactionExpired) && r.tx != nil {
+			r.tx.Rollback()
+			r.tx = nil
+		}
 		fmt.Fprintf(r.out, "error: %s\n", err)
 	}
 }
 
 func (r *Repl) executeOperation(op any) error {
+	if o, ok := op.(Operation); ok && isWriteOperation(o) {
+		// A dry run writes nothing, so it needs neither write access nor
+		// a confirmation.
+		if r.dryRun {
+			return r.dryRunWrite(o)
+		}
+		if r.readOnly {
+			return fmt.Errorf("%w: %s is not allowed", ErrReadOnly, o.OperationType())
+		}
+		if r.tx != nil {
+			return r.queueWrite(o)
+		}
+		ok, err := r.confirmWrite(o)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(r.out, "Canceled")
+			return nil
+		}
+	}
+
+	if r.tx != nil {
+		switch v := op.(type) {
+		case *CountOperation, *AggregateOperation, *ExplainOperation:
+			return fmt.Errorf("%s is %w", v.(Operation).OperationType(), ErrNotInTransaction)
+		}
+	}
+
 	switch v := op.(type) {
 	case *MetacommandPager:
 		return r.handlePager(v)
 	case *MetacommandListCollections:
 		return r.handleListCollections(v)
+	case *MetacommandAsOf:
+		return r.handleAsOf(v)
+	case *MetacommandTimezone:
+		return r.handleTimezone(v)
+	case *MetacommandDryRun:
+		return r.handleDryRun(v)
 	case *QueryOperation:
 		return r.handleQuery(v)
 	case *GetOperation:
 		return r.handleGet(v)
 	case *CountOperation:
 		return r.handleCount(v)
+	case *AggregateOperation:
+		return r.handleAggregate(v)
+	case *ExplainOperation:
+		return r.handleExplain(v)
+	case *InsertOperation:
+		return r.handleInsert(v)
+	case *SetOperation:
+		return r.handleSet(v)
+	case *UpdateOperation:
+		return r.handleUpdate(v)
+	case *DeleteOperation:
+		return r.handleDelete(v)
+	case *DropCollectionOperation:
+		return r.handleDropCollection(v)
+	case *CopyOperation:
+		return r.handleCopy(v)
+	case *TransactionOperation:
+		return r.handleTransaction(v)
 	default:
 		return fmt.Errorf("unknown operation type")
 	}
@@ -184,6 +300,25 @@ func (r *Repl) handlePager(op *MetacommandPager) error {
 	return nil
 }
 
+func (r *Repl) handleAsOf(op *MetacommandAsOf) error {
+	r.asOf = op.asOf
+	return nil
+}
+
+func (r *Repl) handleDryRun(op *MetacommandDryRun) error {
+	r.dryRun = op.on
+	return nil
+}
+
+func (r *Repl) handleTimezone(op *MetacommandTimezone) error {
+	if op.location == nil {
+		fmt.Fprintln(r.out, r.location)
+		return nil
+	}
+	r.location = op.location
+	return nil
+}
+
 func (r *Repl) handleListCollections(op *MetacommandListCollections) error {
 	cols, err := r.exe.ExecuteListCollections(r.ctx, op)
 	if err != nil {
@@ -198,108 +333,623 @@ func (r *Repl) handleListCollections(op *MetacommandListCollections) error {
 }
 
 func (r *Repl) handleQuery(op *QueryOperation) error {
-	docs, err := r.exe.ExecuteQuery(r.ctx, op)
+	if op.asOf.IsZero() {
+		op.asOf = r.asOf
+	}
+	if op.IsGroupBy() {
+		return r.handleGroupBy(op)
+	}
+
+	var docs []*firestore.DocumentSnapshot
+	var err error
+	if r.tx != nil {
+		docs, err = r.tx.ExecuteQuery(r.ctx, op)
+	} else {
+		docs, err = r.exe.ExecuteQuery(r.ctx, op)
+	}
 	if err != nil {
 		return err
 	}
 
 	if r.outputMode == OutputModeJSON {
-		r.outputDocsJSON(docs)
+		r.outputDocsJSON(docs, op.asOf)
 	} else if r.outputMode == OutputModeTable {
-		r.outputDocsTable(docs)
+		r.outputDocsTable(docs, op.selects)
+		r.outputReadTime(op.asOf)
 	}
 	return nil
 }
 
-func (r *Repl) handleGet(op *GetOperation) error {
-	doc, err := r.exe.ExecuteGet(r.ctx, op)
+func (r *Repl) handleGroupBy(op *QueryOperation) error {
+	if r.tx != nil {
+		return fmt.Errorf("GROUP BY is %w", ErrNotInTransaction)
+	}
+	rows, err := r.exe.ExecuteGroupBy(r.ctx, op)
 	if err != nil {
 		return err
 	}
 
-	data := doc.Data()
-	if len(op.Selects()) > 0 {
-		filtered := make(map[string]any, len(op.Selects()))
-		for _, s := range op.Selects() {
-			if v, ok := data[s]; ok {
-				filtered[s] = v
-			}
-		}
-		data = filtered
+	if r.outputMode == OutputModeJSON {
+		r.outputRowsJSON(rows)
+	} else if r.outputMode == OutputModeTable {
+		r.outputRowsTable(groupByColumns(op), rows)
+		r.outputReadTime(op.asOf)
+	}
+	return nil
+}
+
+func (r *Repl) handleGet(op *GetOperation) error {
+	if op.asOf.IsZero() {
+		op.asOf = r.asOf
+	}
+
+	var doc *firestore.DocumentSnapshot
+	var err error
+	if r.tx != nil {
+		doc, err = r.tx.ExecuteGet(r.ctx, op)
+	} else {
+		doc, err = r.exe.ExecuteGet(r.ctx, op)
+	}
+	if err != nil {
+		return err
 	}
 
 	if r.outputMode == OutputModeJSON {
-		r.outputDocJSON(doc.Ref.ID, data)
+		r.outputDocJSON(doc.Ref.ID, doc.Data(), doc.UpdateTime, op.asOf)
 	} else if r.outputMode == OutputModeTable {
-		r.outputDocTable(doc.Ref.ID, data)
+		r.outputDocTable(doc.Ref.ID, doc.Data(), op.Selects())
+		r.outputUpdateTime(doc.UpdateTime)
+		r.outputReadTime(op.asOf)
 	}
 	return nil
 }
 
 func (r *Repl) handleCount(op *CountOperation) error {
+	if op.asOf.IsZero() {
+		op.asOf = r.asOf
+	}
+
 	count, err := r.exe.ExecuteCount(r.ctx, op)
 	if err != nil {
 		return err
 	}
 
 	fmt.Fprintf(r.out, "%d\n", count)
+	if r.outputMode == OutputModeTable {
+		r.outputReadTime(op.asOf)
+	}
+	return nil
+}
+
+func (r *Repl) handleExplain(op *ExplainOperation) error {
+	if op.query.asOf.IsZero() {
+		op.query.asOf = r.asOf
+	}
+
+	metrics, err := r.exe.ExecuteExplain(r.ctx, op)
+	if err != nil {
+		return err
+	}
+
+	if r.outputMode == OutputModeJSON {
+		r.outputExplainJSON(metrics)
+	} else if r.outputMode == OutputModeTable {
+		r.outputExplainTable(metrics)
+	}
+	return nil
+}
+
+func (r *Repl) handleInsert(op *InsertOperation) error {
+	result, err := r.exe.ExecuteInsert(r.ctx, op)
+	if err != nil {
+		return err
+	}
+	r.outputWriteResults([]*WriteResult{result})
 	return nil
 }
 
+func (r *Repl) handleSet(op *SetOperation) error {
+	result, err := r.exe.ExecuteSet(r.ctx, op)
+	if err != nil {
+		return err
+	}
+	r.outputWriteResults([]*WriteResult{result})
+	return nil
+}
+
+func (r *Repl) handleUpdate(op *UpdateOperation) error {
+	if op.IsBulk() {
+		result, err := r.exe.ExecuteBulkUpdate(r.ctx, op, r.bulkWriteProgress)
+		if err != nil {
+			return err
+		}
+		r.outputBulkWriteResult("Updated", result)
+		return nil
+	}
+
+	result, err := r.exe.ExecuteUpdate(r.ctx, op)
+	if err != nil {
+		return err
+	}
+	r.outputWriteResults([]*WriteResult{result})
+	return nil
+}
+
+func (r *Repl) handleDelete(op *DeleteOperation) error {
+	if op.IsBulk() {
+		result, err := r.exe.ExecuteBulkDelete(r.ctx, op, r.bulkWriteProgress)
+		if err != nil {
+			return err
+		}
+		r.outputBulkWriteResult("Deleted", result)
+		return nil
+	}
+
+	if op.IsRecursive() {
+		result, err := r.exe.ExecuteRecursiveDelete(r.ctx, op, r.bulkWriteProgress)
+		// a failed delete still shows what was deleted before it failed
+		if result != nil {
+			r.outputRecursiveDeleteResult(result)
+		}
+		return err
+	}
+
+	result, err := r.exe.ExecuteDelete(r.ctx, op)
+	if err != nil {
+		return err
+	}
+	r.outputWriteResults([]*WriteResult{result})
+	return nil
+}
+
+func (r *Repl) handleDropCollection(op *DropCollectionOperation) error {
+	result, err := r.exe.ExecuteDropCollection(r.ctx, op, r.bulkWriteProgress)
+	if result != nil {
+		r.outputRecursiveDeleteResult(result)
+	}
+	return err
+}
+
+func (r *Repl) handleCopy(op *CopyOperation) error {
+	result, err := r.exe.ExecuteCopy(r.ctx, op, r.bulkWriteProgress)
+	if result != nil {
+		r.outputCopyResult(result)
+	}
+	return err
+}
+
+// handleTransaction runs BEGIN, COMMIT and ROLLBACK. The transaction is
+// closed once COMMIT has run, whether it succeeded or not.
+func (r *Repl) handleTransaction(op *TransactionOperation) error {
+	if op.OperationType() == OPERATION_TYPE_BEGIN {
+		if r.tx != nil {
+			return errors.New("a transaction is already open")
+		}
+		r.tx = NewTransaction(r.exe, r.transactionRetry)
+		return nil
+	}
+
+	tx := r.tx
+	if tx == nil {
+		return ErrNoTransaction
+	}
+
+	if op.OperationType() == OPERATION_TYPE_ROLLBACK {
+		r.tx = nil
+		if err := tx.Rollback(); err != nil {
+			return err
+		}
+		if r.outputMode == OutputModeTable {
+			fmt.Fprintln(r.out, "Rolled back")
+		}
+		return nil
+	}
+
+	ok, err := r.confirmCommit(tx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Fprintln(r.out, "Canceled")
+		return nil
+	}
+
+	r.tx = nil
+	results, err := tx.Commit(r.ctx)
+	if err != nil {
+		return fmt.Errorf("transaction rolled back: %w", err)
+	}
+	if len(results) > 0 {
+		r.outputWriteResults(results)
+	}
+	if r.outputMode == OutputModeTable {
+		if tx.Attempts() > 1 {
+			fmt.Fprintf(r.out, "Committed after %d attempts\n", tx.Attempts())
+		} else {
+			fmt.Fprintln(r.out, "Committed")
+		}
+	}
+	return nil
+}
+
+// queueWrite adds op to the open transaction.
+func (r *Repl) queueWrite(op Operation) error {
+	if err := r.tx.Queue(op); err != nil {
+		return err
+	}
+	if r.outputMode == OutputModeTable {
+		target, err := r.writeTarget(op)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "Queued %s\n", target)
+	}
+	return nil
+}
+
+// confirmCommit lists the queued writes of tx and asks whether to commit
+// them.
+func (r *Repl) confirmCommit(tx *Transaction) (bool, error) {
+	writes := tx.Writes()
+	if !r.confirmWrites || len(writes) == 0 {
+		return true, nil
+	}
+	message := fmt.Sprintf("COMMIT %d writes:", len(writes))
+	for _, op := range writes {
+		target, err := r.writeTarget(op)
+		if err != nil {
+			return false, err
+		}
+		message += "\n  " + target
+	}
+	return r.confirm(message)
+}
+
+// transactionRetry reports that a concurrent write aborted the transaction
+// and it is run again.
+func (r *Repl) transactionRetry(attempt int) {
+	fmt.Fprintf(r.out, "Transaction aborted by a concurrent write, retrying (attempt %d of %d)\n", attempt, firestore.DefaultTransactionMaxAttempts)
+}
+
+// closeTransaction rolls back a transaction left open when the input ends.
+func (r *Repl) closeTransaction() {
+	if r.tx == nil {
+		return
+	}
+	if err := r.handleTransaction(NewTransactionOperation(OPERATION_TYPE_ROLLBACK)); err != nil {
+		fmt.Fprintf(r.out, "error: %s\n", err)
+	}
+}
+
+// dryRunWrite shows what op would change without writing. Recursive
+// deletes are shown as the number of documents per collection.
+func (r *Repl) dryRunWrite(op Operation) error {
+	var changes []DocumentChange
+	var err error
+	switch v := op.(type) {
+	case *InsertOperation:
+		changes, err = r.exe.DryRunInsert(r.ctx, v)
+	case *SetOperation:
+		changes, err = r.exe.DryRunSet(r.ctx, v)
+	case *UpdateOperation:
+		changes, err = r.exe.DryRunUpdate(r.ctx, v)
+	case *DeleteOperation:
+		if v.IsRecursive() {
+			result, err := r.exe.DryRunRecursiveDelete(r.ctx, v)
+			if err != nil {
+				return err
+			}
+			r.outputRecursiveDeleteResult(result)
+			r.outputDryRunNote()
+			return nil
+		}
+		changes, err = r.exe.DryRunDelete(r.ctx, v)
+	case *DropCollectionOperation:
+		result, err := r.exe.DryRunDropCollection(r.ctx, v)
+		if err != nil {
+			return err
+		}
+		r.outputRecursiveDeleteResult(result)
+		r.outputDryRunNote()
+		return nil
+	case *CopyOperation:
+		result, err := r.exe.DryRunCopy(r.ctx, v)
+		if err != nil {
+			return err
+		}
+		r.outputCopyResult(result)
+		r.outputDryRunNote()
+		return nil
+	default:
+		return fmt.Errorf("%s cannot be dry run", op.OperationType())
+	}
+	if err != nil {
+		return err
+	}
+	r.outputDocumentChanges(changes)
+	return nil
+}
+
+// confirmWrite shows what op is going to write and asks whether to go on.
+// Without confirmations, or when a bulk write matches nothing, it does not
+// ask.
+func (r *Repl) confirmWrite(op Operation) (bool, error) {
+	if !r.confirmWrites {
+		return true, nil
+	}
+	target, err := r.writeTarget(op)
+	if err != nil {
+		return false, err
+	}
+	if target == "" {
+		return true, nil
+	}
+	return r.confirm(target)
+}
+
+// writeTarget describes what op is going to write. It is empty when a bulk
+// write matches nothing.
+func (r *Repl) writeTarget(op Operation) (string, error) {
+	var target string
+	switch v := op.(type) {
+	case *InsertOperation:
+		if v.docId == "" {
+			target = fmt.Sprintf("INSERT a new document into %s", v.collection)
+		} else {
+			target = fmt.Sprintf("INSERT %s/%s", v.collection, v.docId)
+		}
+	case *SetOperation:
+		target = fmt.Sprintf("SET %s/%s", v.collection, v.docId)
+		if v.merge {
+			target += " (merge)"
+		}
+		target += preconditionSuffix(v.precondition)
+	case *UpdateOperation:
+		if !v.IsBulk() {
+			target = fmt.Sprintf("UPDATE %s/%s", v.collection, v.docId) + preconditionSuffix(v.precondition)
+			break
+		}
+		count, err := r.exe.CountBulkTargets(r.ctx, v.query)
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "", nil
+		}
+		target = fmt.Sprintf("UPDATE %d documents in %s", count, v.collection)
+	case *DeleteOperation:
+		if v.IsBulk() {
+			count, err := r.exe.CountBulkTargets(r.ctx, v.query)
+			if err != nil {
+				return "", err
+			}
+			if count == 0 {
+				return "", nil
+			}
+			target = fmt.Sprintf("DELETE %d documents in %s", count, v.collection)
+		} else if v.recursive {
+			target = fmt.Sprintf("DELETE %s/%s and its subcollections", v.collection, v.docId)
+		} else {
+			target = fmt.Sprintf("DELETE %s/%s", v.collection, v.docId) + preconditionSuffix(v.precondition)
+		}
+	case *DropCollectionOperation:
+		count, err := r.exe.ExecuteCount(r.ctx, NewCountOperation(v.collection, nil))
+		if err != nil {
+			return "", err
+		}
+		target = fmt.Sprintf("DROP COLLECTION %s: %d documents and their subcollections", v.collection, count)
+	case *CopyOperation:
+		if !v.IsCollection() {
+			target = fmt.Sprintf("COPY %s/%s to %s/%s", v.collection, v.docId, v.toCollection, v.toDocId)
+			if v.recursive {
+				target += " with its subcollections"
+			}
+			break
+		}
+		count, err := r.exe.CountBulkTargets(r.ctx, v.query)
+		if err != nil {
+			return "", err
+		}
+		if count == 0 && !v.recursive {
+			return "", nil
+		}
+		target = fmt.Sprintf("COPY %d documents from %s to %s", count, v.collection, v.toCollection)
+		if v.recursive {
+			target += " with their subcollections"
+		}
+	default:
+		target = string(op.OperationType())
+	}
+	return target, nil
+}
+
+// preconditionSuffix is the IF clause of a write target, if any.
+func preconditionSuffix(precondition *Precondition) string {
+	if precondition == nil {
+		return ""
+	}
+	return " " + precondition.String()
+}
+
+// confirm shows message and asks whether to go on.
+func (r *Repl) confirm(message string) (bool, error) {
+	fmt.Fprintf(r.out, "%s\nContinue? [y/N] ", message)
+	answer, err := bufio.NewReader(r.in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// bulkWriteProgressInterval is how many documents are written between
+// progress lines.
+const bulkWriteProgressInterval = 100
+
+// bulkWriteProgress shows the progress of a bulk write in table mode.
+// A total of 0 means the total is not known in advance.
+func (r *Repl) bulkWriteProgress(done int, total int) {
+	if r.outputMode != OutputModeTable || done%bulkWriteProgressInterval != 0 || done == total {
+		return
+	}
+	if total == 0 {
+		fmt.Fprintf(r.out, "%d documents\n", done)
+		return
+	}
+	fmt.Fprintf(r.out, "%d/%d documents\n", done, total)
+}
+
+// outputUpdateTime shows when a document was last updated, in full
+// precision so that it can be used in IF UPDATED_AT.
+func (r *Repl) outputUpdateTime(updateTime time.Time) {
+	if updateTime.IsZero() {
+		return
+	}
+	fmt.Fprintf(r.out, "(updated at %s)\n", updateTime.In(r.location).Format(time.RFC3339Nano))
+}
+
+// outputReadTime notes the past read time below a table.
+func (r *Repl) outputReadTime(asOf time.Time) {
+	if asOf.IsZero() {
+		return
+	}
+	fmt.Fprintf(r.out, "(as of %s)\n", asOf.In(r.location).Format(time.RFC3339))
+}
+
+func (r *Repl) handleAggregate(op *AggregateOperation) error {
+	// Aggregation queries are always read at the latest time.
+	if !r.asOf.IsZero() {
+		return fmt.Errorf("AGGREGATE cannot read as of a past time; turn it off with \\asof off")
+	}
+
+	results, err := r.exe.ExecuteAggregate(r.ctx, op)
+	if err != nil {
+		return err
+	}
+
+	if r.outputMode == OutputModeJSON {
+		r.outputAggregateJSON(results)
+	} else if r.outputMode == OutputModeTable {
+		r.outputAggregateTable(op.Aggregates(), results)
+	}
+	return nil
+}
+
+// fieldPathString formats a field path with dots, quoting segments
+// that contain dots or backticks.
+func fieldPathString(fp firestore.FieldPath) string {
+	segments := make([]string, 0, len(fp))
+	for _, segment := range fp {
+		if strings.ContainsAny(segment, ".`") {
+			segment = "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(segment) + "`"
+		}
+		segments = append(segments, segment)
+	}
+	return strings.Join(segments, ".")
+}
+
+func lookupFieldPath(data map[string]any, fp firestore.FieldPath) (any, bool) {
+	var v any = data
+	for _, segment := range fp {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[segment]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
 func (r *Repl) ProcessLineFromPipe() {
 	scanner := bufio.NewScanner(r.in)
 	for scanner.Scan() {
 		line := scanner.Text()
 		r.ProcessLine(line)
 	}
+	r.closeTransaction()
 }
 
-func (r *Repl) outputDocsTable(docs []*firestore.DocumentSnapshot) {
+func (r *Repl) outputDocsTable(docs []*firestore.DocumentSnapshot, selects []string) {
 	out, render := r.pagerableOut()
 	defer render()
 
-	// collect keys
-	keys := []string{}
+	data := make([]map[string]any, 0, len(docs))
 	for _, doc := range docs {
-		for k := range doc.Data() {
-			if !slices.Contains(keys, k) {
-				keys = append(keys, k)
-			}
-		}
+		data = append(data, doc.Data())
 	}
-	slices.Sort(keys)
+	columns := tableColumns(data, selects)
 
 	table := tablewriter.NewTable(out, tablewriter.WithConfig(r.tableConfig()))
-	table.Header(append([]string{"ID"}, keys...))
+	table.Header(tableHeader(columns))
 
-	for _, doc := range docs {
-		row := []string{doc.Ref.ID}
-		for _, k := range keys {
-			val, ok := doc.Data()[k]
-			row = append(row, r.toTableCell(val, ok))
-		}
-		table.Append(row)
+	for i, doc := range docs {
+		table.Append(r.tableRow(doc.Ref.ID, data[i], columns))
 	}
 	table.Render()
 }
 
-func (r *Repl) outputDocTable(id string, data map[string]any) {
+func (r *Repl) outputDocTable(id string, data map[string]any, selects []string) {
+	columns := tableColumns([]map[string]any{data}, selects)
+
+	table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+	table.Header(tableHeader(columns))
+	table.Append(r.tableRow(id, data, columns))
+	table.Render()
+}
+
+type tableColumn struct {
+	header string
+	path   firestore.FieldPath
+}
+
+// tableColumns returns one column per selected field so that nested values
+// get their own columns. Without selects, top-level keys are used.
+func tableColumns(data []map[string]any, selects []string) []tableColumn {
+	columns := []tableColumn{}
+	if len(selects) > 0 {
+		for _, s := range selects {
+			fp, err := parseFieldPath(s)
+			if err != nil {
+				continue
+			}
+			columns = append(columns, tableColumn{fieldPathString(fp), fp})
+		}
+		return columns
+	}
+
 	keys := []string{}
-	for k := range data {
-		keys = append(keys, k)
+	for _, d := range data {
+		for k := range d {
+			if !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
 	}
 	slices.Sort(keys)
 
-	table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
-	table.Header(append([]string{"ID"}, keys...))
+	for _, k := range keys {
+		columns = append(columns, tableColumn{k, firestore.FieldPath{k}})
+	}
+	return columns
+}
 
+func tableHeader(columns []tableColumn) []string {
+	header := []string{"ID"}
+	for _, col := range columns {
+		header = append(header, col.header)
+	}
+	return header
+}
+
+func (r *Repl) tableRow(id string, data map[string]any, columns []tableColumn) []string {
 	row := []string{id}
-	for _, k := range keys {
-		val, ok := data[k]
+	for _, col := range columns {
+		val, ok := lookupFieldPath(data, col.path)
 		row = append(row, r.toTableCell(val, ok))
 	}
-	table.Append(row)
-	table.Render()
+	return row
 }
 
 func (r *Repl) tableConfig() tablewriter.Config {
@@ -310,17 +960,19 @@ func (r *Repl) tableConfig() tablewriter.Config {
 	}
 }
 
-func (r *Repl) outputDocsJSON(docs []*firestore.DocumentSnapshot) {
+func (r *Repl) outputDocsJSON(docs []*firestore.DocumentSnapshot, asOf time.Time) {
 	type docOutput struct {
-		ID   string         `json:"id"`
-		Data map[string]any `json:"data"`
+		ID       string         `json:"id"`
+		Data     map[string]any `json:"data"`
+		ReadTime *time.Time     `json:"readTime,omitempty"`
 	}
 
 	outputs := make([]docOutput, 0, len(docs))
 	for _, doc := range docs {
 		outputs = append(outputs, docOutput{
-			ID:   doc.Ref.ID,
-			Data: doc.Data(),
+			ID:       doc.Ref.ID,
+			Data:     toJSONData(doc.Data()),
+			ReadTime: optionalTime(asOf),
 		})
 	}
 
@@ -332,13 +984,17 @@ func (r *Repl) outputDocsJSON(docs []*firestore.DocumentSnapshot) {
 	fmt.Fprintln(r.out, string(j))
 }
 
-func (r *Repl) outputDocJSON(id string, data map[string]any) {
+func (r *Repl) outputDocJSON(id string, data map[string]any, updateTime time.Time, asOf time.Time) {
 	output := struct {
-		ID   string         `json:"id"`
-		Data map[string]any `json:"data"`
+		ID         string         `json:"id"`
+		Data       map[string]any `json:"data"`
+		UpdateTime *time.Time     `json:"updateTime,omitempty"`
+		ReadTime   *time.Time     `json:"readTime,omitempty"`
 	}{
-		ID:   id,
-		Data: data,
+		ID:         id,
+		Data:       toJSONData(data),
+		UpdateTime: optionalTime(updateTime),
+		ReadTime:   optionalTime(asOf),
 	}
 
 	j, err := json.Marshal(output)
@@ -349,6 +1005,323 @@ func (r *Repl) outputDocJSON(id string, data map[string]any) {
 	fmt.Fprintln(r.out, string(j))
 }
 
+func (r *Repl) outputRowsJSON(rows []map[string]any) {
+	outputs := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		outputs = append(outputs, toJSONData(row))
+	}
+	j, err := json.Marshal(outputs)
+	if err != nil {
+		fmt.Fprintf(r.out, "invalid data: %s\n", err)
+		return
+	}
+	fmt.Fprintln(r.out, string(j))
+}
+
+func (r *Repl) outputRowsTable(columns []string, rows []map[string]any) {
+	out, render := r.pagerableOut()
+	defer render()
+
+	table := tablewriter.NewTable(out, tablewriter.WithConfig(r.tableConfig()))
+	table.Header(columns)
+	for _, row := range rows {
+		cells := make([]string, 0, len(columns))
+		for _, column := range columns {
+			val, ok := row[column]
+			cells = append(cells, r.toTableCell(val, ok))
+		}
+		table.Append(cells)
+	}
+	table.Render()
+}
+
+// outputWriteResults shows the written documents. JSON output is a single
+// object when one document was written.
+func (r *Repl) outputWriteResults(results []*WriteResult) {
+	if r.outputMode == OutputModeJSON {
+		var output any = results
+		if len(results) == 1 {
+			output = results[0]
+		}
+		j, err := json.Marshal(output)
+		if err != nil {
+			fmt.Fprintf(r.out, "invalid data: %s\n", err)
+			return
+		}
+		fmt.Fprintln(r.out, string(j))
+	} else if r.outputMode == OutputModeTable {
+		table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+		table.Header([]string{"Path", "Update Time"})
+		for _, result := range results {
+			table.Append([]string{result.Path, r.toTableCell(result.UpdateTime, true)})
+		}
+		table.Render()
+	}
+}
+
+// outputBulkWriteResult shows how many documents were written and which
+// ones failed.
+func (r *Repl) outputBulkWriteResult(verb string, result *BulkWriteResult) {
+	if r.outputMode == OutputModeJSON {
+		j, err := json.Marshal(result)
+		if err != nil {
+			fmt.Fprintf(r.out, "invalid data: %s\n", err)
+			return
+		}
+		fmt.Fprintln(r.out, string(j))
+	} else if r.outputMode == OutputModeTable {
+		fmt.Fprintf(r.out, "%s %d of %d documents", verb, result.Succeeded, result.Matched)
+		if len(result.Failures) == 0 {
+			fmt.Fprintln(r.out)
+			return
+		}
+		fmt.Fprintf(r.out, ", %d failed\n", len(result.Failures))
+		r.outputFailuresTable(result.Failures)
+	}
+}
+
+func (r *Repl) outputFailuresTable(failures []BulkWriteFailure) {
+	table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+	table.Header([]string{"Path", "Error"})
+	for _, failure := range failures {
+		table.Append([]string{failure.Path, failure.Error})
+	}
+	table.Render()
+}
+
+// outputDocumentChanges shows a diff per document in table mode, with "-"
+// for a value before and "+" for a value after the write.
+func (r *Repl) outputDocumentChanges(changes []DocumentChange) {
+	if r.outputMode == OutputModeJSON {
+		outputs := make([]DocumentChange, 0, len(changes))
+		for _, change := range changes {
+			outputs = append(outputs, DocumentChange{
+				Path:   change.Path,
+				Before: toJSONDocument(change.Before),
+				After:  toJSONDocument(change.After),
+			})
+		}
+		j, err := json.Marshal(outputs)
+		if err != nil {
+			fmt.Fprintf(r.out, "invalid data: %s\n", err)
+			return
+		}
+		fmt.Fprintln(r.out, string(j))
+	} else if r.outputMode == OutputModeTable {
+		for _, change := range changes {
+			header := change.Path
+			if change.Before == nil {
+				header += " (new)"
+			} else if change.After == nil {
+				header += " (deleted)"
+			}
+			fmt.Fprintln(r.out, header)
+
+			diffs := diffData(change.Before, change.After)
+			if len(diffs) == 0 {
+				fmt.Fprintln(r.out, "  (no changes)")
+			}
+			for _, diff := range diffs {
+				if diff.hasBefore {
+					fmt.Fprintf(r.out, "- %s: %s\n", diff.path, r.diffValue(diff.before))
+				}
+				if diff.hasAfter {
+					fmt.Fprintf(r.out, "+ %s: %s\n", diff.path, r.diffValue(diff.after))
+				}
+			}
+			fmt.Fprintln(r.out)
+		}
+		fmt.Fprintf(r.out, "%d documents would change\n", len(changes))
+		r.outputDryRunNote()
+	}
+}
+
+// outputDryRunNote notes below a table that nothing was written.
+func (r *Repl) outputDryRunNote() {
+	if r.outputMode != OutputModeTable {
+		return
+	}
+	fmt.Fprintln(r.out, "(dry run, nothing was written)")
+}
+
+// diffValue formats a value like a table cell, with strings quoted so that
+// they stand out from other types.
+func (r *Repl) diffValue(val any) string {
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return r.toTableCell(val, true)
+}
+
+// toJSONDocument is toJSONData that keeps a missing document nil.
+func toJSONDocument(data map[string]any) map[string]any {
+	if data == nil {
+		return nil
+	}
+	return toJSONData(data)
+}
+
+// outputRecursiveDeleteResult shows the number of deleted documents per
+// collection and the documents that failed.
+func (r *Repl) outputRecursiveDeleteResult(result *RecursiveDeleteResult) {
+	if r.outputMode == OutputModeJSON {
+		j, err := json.Marshal(result)
+		if err != nil {
+			fmt.Fprintf(r.out, "invalid data: %s\n", err)
+			return
+		}
+		fmt.Fprintln(r.out, string(j))
+	} else if r.outputMode == OutputModeTable {
+		table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+		table.Header([]string{"Collection", "Deleted"})
+		for _, collection := range result.Collections {
+			table.Append([]string{collection.Path, strconv.Itoa(collection.Deleted)})
+		}
+		table.Render()
+
+		if len(result.Failures) == 0 {
+			return
+		}
+		fmt.Fprintf(r.out, "%d failed\n", len(result.Failures))
+		r.outputFailuresTable(result.Failures)
+	}
+}
+
+// outputCopyResult shows the number of copied documents per target
+// collection and the documents that failed.
+func (r *Repl) outputCopyResult(result *CopyResult) {
+	if r.outputMode == OutputModeJSON {
+		j, err := json.Marshal(result)
+		if err != nil {
+			fmt.Fprintf(r.out, "invalid data: %s\n", err)
+			return
+		}
+		fmt.Fprintln(r.out, string(j))
+	} else if r.outputMode == OutputModeTable {
+		table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+		table.Header([]string{"Collection", "Copied"})
+		for _, collection := range result.Collections {
+			table.Append([]string{collection.Path, strconv.Itoa(collection.Copied)})
+		}
+		table.Render()
+
+		if len(result.Failures) == 0 {
+			return
+		}
+		fmt.Fprintf(r.out, "%d failed\n", len(result.Failures))
+		r.outputFailuresTable(result.Failures)
+	}
+}
+
+type explainOutput struct {
+	IndexesUsed    []map[string]any    `json:"indexesUsed"`
+	ExecutionStats *explainStatsOutput `json:"executionStats,omitempty"`
+}
+
+type explainStatsOutput struct {
+	ResultsReturned     int64          `json:"resultsReturned"`
+	DocumentsScanned    any            `json:"documentsScanned,omitempty"`
+	IndexEntriesScanned any            `json:"indexEntriesScanned,omitempty"`
+	ReadOperations      int64          `json:"readOperations"`
+	ExecutionDuration   string         `json:"executionDuration,omitempty"`
+	DebugStats          map[string]any `json:"debugStats,omitempty"`
+}
+
+// newExplainOutput flattens explain metrics. Scan counts only exist in the
+// debug stats of ANALYZE.
+func newExplainOutput(metrics *firestore.ExplainMetrics) explainOutput {
+	output := explainOutput{IndexesUsed: []map[string]any{}}
+	if metrics.PlanSummary != nil {
+		for _, index := range metrics.PlanSummary.IndexesUsed {
+			if index != nil {
+				output.IndexesUsed = append(output.IndexesUsed, *index)
+			}
+		}
+	}
+
+	if stats := metrics.ExecutionStats; stats != nil {
+		output.ExecutionStats = &explainStatsOutput{
+			ResultsReturned: stats.ResultsReturned,
+			ReadOperations:  stats.ReadOperations,
+		}
+		if stats.ExecutionDuration != nil {
+			output.ExecutionStats.ExecutionDuration = stats.ExecutionDuration.String()
+		}
+		if stats.DebugStats != nil {
+			debugStats := *stats.DebugStats
+			output.ExecutionStats.DocumentsScanned = debugStats["documents_scanned"]
+			output.ExecutionStats.IndexEntriesScanned = debugStats["index_entries_scanned"]
+			output.ExecutionStats.DebugStats = debugStats
+		}
+	}
+	return output
+}
+
+func (r *Repl) outputExplainJSON(metrics *firestore.ExplainMetrics) {
+	j, err := json.Marshal(newExplainOutput(metrics))
+	if err != nil {
+		fmt.Fprintf(r.out, "invalid data: %s\n", err)
+		return
+	}
+	fmt.Fprintln(r.out, string(j))
+}
+
+func (r *Repl) outputExplainTable(metrics *firestore.ExplainMetrics) {
+	output := newExplainOutput(metrics)
+
+	indexes := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+	indexes.Header([]string{"Query Scope", "Properties"})
+	for _, index := range output.IndexesUsed {
+		indexes.Append([]string{fmt.Sprint(index["query_scope"]), fmt.Sprint(index["properties"])})
+	}
+	indexes.Render()
+
+	stats := output.ExecutionStats
+	if stats == nil {
+		return
+	}
+	table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+	table.Header([]string{"Statistic", "Value"})
+	table.Append([]string{"Results returned", fmt.Sprint(stats.ResultsReturned)})
+	table.Append([]string{"Documents scanned", r.toTableCell(stats.DocumentsScanned, stats.DocumentsScanned != nil)})
+	table.Append([]string{"Index entries scanned", r.toTableCell(stats.IndexEntriesScanned, stats.IndexEntriesScanned != nil)})
+	table.Append([]string{"Read operations", fmt.Sprint(stats.ReadOperations)})
+	table.Append([]string{"Execution duration", stats.ExecutionDuration})
+	table.Render()
+}
+
+func (r *Repl) outputAggregateJSON(results map[string]any) {
+	j, err := json.Marshal(results)
+	if err != nil {
+		fmt.Fprintf(r.out, "invalid data: %s\n", err)
+		return
+	}
+	fmt.Fprintln(r.out, string(j))
+}
+
+func (r *Repl) outputAggregateTable(aggregates []Aggregate, results map[string]any) {
+	header := make([]string, 0, len(aggregates))
+	row := make([]string, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		val, ok := results[aggregate.Alias()]
+		header = append(header, aggregate.Alias())
+		row = append(row, r.toTableCell(val, ok))
+	}
+
+	table := tablewriter.NewTable(r.out, tablewriter.WithConfig(r.tableConfig()))
+	table.Header(header)
+	table.Append(row)
+	table.Render()
+}
+
+// optionalTime returns nil for the zero time, which JSON output omits.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 func (r *Repl) toTableCell(val any, ok bool) string {
 	if !ok {
 		return "(undefined)"
@@ -359,8 +1332,24 @@ func (r *Repl) toTableCell(val any, ok bool) string {
 		return fmt.Sprintf("%v", v)
 	case nil:
 		return "(null)"
+	case time.Time:
+		return v.In(r.location).Format(time.RFC3339Nano)
+	case *firestore.DocumentRef:
+		return fmt.Sprintf("REF(%q)", refPath(v))
+	case *latlng.LatLng:
+		return fmt.Sprintf("GEOPOINT(%s, %s)", formatFloat(v.GetLatitude()), formatFloat(v.GetLongitude()))
+	case []byte:
+		return fmt.Sprintf("BYTES(%q)", base64.StdEncoding.EncodeToString(v))
+	case firestore.Vector64:
+		return formatVector(v)
+	case firestore.Vector32:
+		vector := make([]float64, 0, len(v))
+		for _, f := range v {
+			vector = append(vector, float64(f))
+		}
+		return formatVector(vector)
 	default:
-		j, err := json.Marshal(v)
+		j, err := json.Marshal(toJSONValue(v))
 		if err != nil {
 			return "(invalid)"
 		}
@@ -368,6 +1357,59 @@ func (r *Repl) toTableCell(val any, ok bool) string {
 	}
 }
 
+// toJSONData applies toJSONValue to each field of data.
+func toJSONData(data map[string]any) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		out[k] = toJSONValue(v)
+	}
+	return out
+}
+
+// toJSONValue replaces values that do not marshal readably: a document
+// reference becomes its path and a geo point a latitude/longitude object.
+// Bytes already marshal as base64.
+func toJSONValue(val any) any {
+	switch v := val.(type) {
+	case *firestore.DocumentRef:
+		return refPath(v)
+	case *latlng.LatLng:
+		return map[string]float64{"latitude": v.GetLatitude(), "longitude": v.GetLongitude()}
+	case map[string]any:
+		return toJSONData(v)
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			out = append(out, toJSONValue(item))
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// vectorPreviewSize is the number of leading elements shown for a vector
+// in table mode.
+const vectorPreviewSize = 3
+
+// formatVector renders a vector compactly as its dimension and first
+// elements, such as VECTOR(768)[0.12, -0.5, 1, …].
+func formatVector(vector []float64) string {
+	elems := make([]string, 0, vectorPreviewSize+1)
+	for i, f := range vector {
+		if i == vectorPreviewSize {
+			elems = append(elems, "…")
+			break
+		}
+		elems = append(elems, strconv.FormatFloat(f, 'g', 4, 64))
+	}
+	return fmt.Sprintf("VECTOR(%d)[%s]", len(vector), strings.Join(elems, ", "))
+}
+
 func (r *Repl) writeHistory(line string) error {
 	configDirs := configdir.New(VENDOR_NAME, APP_NAME)
 	folders := configDirs.QueryFolders(configdir.Global)
@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/c-bata/go-prompt"
+	"github.com/olekukonko/tablewriter/pkg/twwidth"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 func TestRepl_ProcessLineFromPipe(t *testing.T) {
@@ -24,7 +28,7 @@ func TestRepl_ProcessLineFromPipe(t *testing.T) {
 
 	// Seed data
 	docRef := fs.Collection("users").Doc("testuser")
-	_, err = docRef.Set(ctx, map[string]interface{}{
+	wr, err := docRef.Set(ctx, map[string]interface{}{
 		"name": "test user",
 		"age":  30,
 	})
@@ -41,6 +45,497 @@ func TestRepl_ProcessLineFromPipe(t *testing.T) {
 	repl := NewRepl(ctx, fs, &stdin, &stdout, OutputModeJSON)
 	repl.ProcessLineFromPipe()
 
-	expectedJSON := `{"id":"testuser","data":{"age":30,"name":"test user"}}`
+	expectedJSON := `{"id":"testuser","data":{"age":30,"name":"test user"},"updateTime":"` + wr.UpdateTime.Format(time.RFC3339Nano) + `"}`
 	assert.JSONEq(t, expectedJSON, stdout.String())
 }
+
+func TestDeleteWordWithSlash(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		want  string
+	}{
+		{
+			desc:  "path segment",
+			input: "GET users/abc",
+			want:  "GET users/",
+		},
+		{
+			desc:  "wide characters",
+			input: "GET ユーザー/山田",
+			want:  "GET ユーザー/",
+		},
+		{
+			desc:  "trailing slash",
+			input: "GET ユーザー/",
+			want:  "GET ",
+		},
+		{
+			desc:  "ideographic space",
+			input: "QUERY\u3000ユーザー",
+			want:  "QUERY\u3000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			buf := prompt.NewBuffer()
+			buf.InsertText(tt.input, false, true)
+			deleteWordWithSlash(buf)
+			assert.Equal(t, tt.want, buf.Text())
+		})
+	}
+}
+
+func TestRepl_OutputDocTableWideCharacters(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputDocTable("山田", map[string]any{"名前": "ユーザー", "emoji": "👍"}, nil)
+
+	lines := strings.Split(strings.TrimRight(stdout.String(), "\n"), "\n")
+	for _, line := range lines {
+		assert.Equal(t, twwidth.Width(lines[0]), twwidth.Width(line), line)
+	}
+}
+
+func TestRepl_OutputDocTableNestedSelects(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	data := map[string]any{
+		"address": map[string]any{"city": "Tokyo", "zip": "100-0001"},
+		"a.b":     map[string]any{"c": int64(1)},
+	}
+	repl.outputDocTable("1", data, []string{"address.city", "`a.b`.c", "address.street"})
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"ID", "address.city", "`a.b`.c", "address.street"}, strings.Fields(strings.ReplaceAll(lines[1], "│", "")))
+	assert.Equal(t, []string{"1", "Tokyo", "1", "(undefined)"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+}
+
+func TestRepl_OutputVector(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	data := map[string]any{
+		"embedding": firestore.Vector64{0.125, -2, 3.14159, 4},
+		"small":     firestore.Vector32{1, 2},
+	}
+	repl.outputDocTable("1", data, nil)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"1", "VECTOR(4)[0.125,", "-2,", "3.142,", "…]", "VECTOR(2)[1,", "2]"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+}
+
+func TestRepl_OutputLiterals(t *testing.T) {
+	data := map[string]any{
+		"author":   &firestore.DocumentRef{ID: "abc", Path: "projects/p/databases/(default)/documents/users/abc"},
+		"hash":     []byte{1, 2, 3},
+		"location": &latlng.LatLng{Latitude: 35.6, Longitude: 0},
+		"tags":     []any{&firestore.DocumentRef{ID: "t", Path: "projects/p/databases/(default)/documents/tags/t"}},
+	}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputDocTable("1", data, nil)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"1", `REF("users/abc")`, `BYTES("AQID")`, "GEOPOINT(35.6,", "0)", `["tags/t"]`}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+
+	stdout.Reset()
+	repl.outputDocJSON("1", data, time.Time{}, time.Time{})
+	assert.JSONEq(t, `{"id":"1","data":{"author":"users/abc","hash":"AQID","location":{"latitude":35.6,"longitude":0},"tags":["tags/t"]}}`, stdout.String())
+}
+
+func TestRepl_OutputWriteResult(t *testing.T) {
+	results := []*WriteResult{
+		{Path: "users/abc", UpdateTime: time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC)},
+	}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.location = time.FixedZone("+09:00", 9*60*60)
+	repl.outputWriteResults(results)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"users/abc", "2024-01-02T19:03:00+09:00"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+
+	stdout.Reset()
+	repl.outputMode = OutputModeJSON
+	repl.outputWriteResults(results)
+	assert.JSONEq(t, `{"path":"users/abc","updateTime":"2024-01-02T10:03:00Z"}`, stdout.String())
+}
+
+func TestRepl_OutputBulkWriteResult(t *testing.T) {
+	result := &BulkWriteResult{
+		Matched:   3,
+		Succeeded: 2,
+		Failures:  []BulkWriteFailure{{Path: "users/c", Error: "not found"}},
+	}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputBulkWriteResult("Updated", result)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, "Updated 2 of 3 documents, 1 failed", lines[0])
+	assert.Equal(t, []string{"users/c", "not", "found"}, strings.Fields(strings.ReplaceAll(lines[4], "│", "")))
+
+	stdout.Reset()
+	repl.outputBulkWriteResult("Deleted", &BulkWriteResult{Matched: 0, Failures: []BulkWriteFailure{}})
+	assert.Equal(t, "Deleted 0 of 0 documents\n", stdout.String())
+
+	stdout.Reset()
+	repl.outputMode = OutputModeJSON
+	repl.outputBulkWriteResult("Updated", result)
+	assert.JSONEq(t, `{"matched":3,"succeeded":2,"failures":[{"path":"users/c","error":"not found"}]}`, stdout.String())
+}
+
+func TestRepl_BulkWriteProgress(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	for i := 1; i <= 250; i++ {
+		repl.bulkWriteProgress(i, 250)
+	}
+	assert.Equal(t, "100/250 documents\n200/250 documents\n", stdout.String())
+
+	stdout.Reset()
+	repl.bulkWriteProgress(500, 0)
+	repl.bulkWriteProgress(523, 0)
+	assert.Equal(t, "500 documents\n", stdout.String())
+
+	stdout.Reset()
+	repl.outputMode = OutputModeJSON
+	repl.bulkWriteProgress(100, 250)
+	assert.Empty(t, stdout.String())
+}
+
+func TestRepl_OutputRecursiveDeleteResult(t *testing.T) {
+	result := &RecursiveDeleteResult{
+		Collections: []DeletedCollection{
+			{Path: "users/abc/posts/1/comments", Deleted: 3},
+			{Path: "users/abc/posts", Deleted: 2},
+			{Path: "users", Deleted: 1},
+		},
+		Failures: []BulkWriteFailure{},
+	}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputRecursiveDeleteResult(result)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"users/abc/posts/1/comments", "3"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+	assert.Equal(t, []string{"users", "1"}, strings.Fields(strings.ReplaceAll(lines[5], "│", "")))
+	assert.NotContains(t, stdout.String(), "failed")
+
+	stdout.Reset()
+	repl.outputMode = OutputModeJSON
+	repl.outputRecursiveDeleteResult(result)
+	assert.JSONEq(t, `{"collections":[{"path":"users/abc/posts/1/comments","deleted":3},{"path":"users/abc/posts","deleted":2},{"path":"users","deleted":1}],"failures":[]}`, stdout.String())
+}
+
+func TestRepl_ReadOnly(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		want  string
+	}{
+		{
+			desc:  "insert",
+			input: `INSERT INTO users VALUES {name: "a"}`,
+			want:  "error: read-only mode: INSERT is not allowed\n",
+		},
+		{
+			desc:  "bulk update",
+			input: `UPDATE users SET name = "a" WHERE age > 20`,
+			want:  "error: read-only mode: UPDATE is not allowed\n",
+		},
+		{
+			desc:  "drop collection",
+			input: `DROP COLLECTION users`,
+			want:  "error: read-only mode: DROP is not allowed\n",
+		},
+		{
+			desc:  "copy",
+			input: `COPY COLLECTION users TO archive`,
+			want:  "error: read-only mode: COPY is not allowed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var stdout bytes.Buffer
+			repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+			repl.SetReadOnly(true)
+			repl.ProcessLine(tt.input)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestRepl_DryRun(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+
+	repl.ProcessLine(`\dryrun on`)
+	assert.True(t, repl.dryRun)
+	prefix, ok := repl.livePrefix()
+	assert.True(t, ok)
+	assert.Equal(t, "dry run> ", prefix)
+
+	repl.ProcessLine(`\asof TIMESTAMP("2024-01-02T10:03:00Z")`)
+	prefix, _ = repl.livePrefix()
+	assert.Equal(t, "dry run, as of 2024-01-02T10:03:00Z> ", prefix)
+
+	repl.ProcessLine(`\dryrun off`)
+	assert.False(t, repl.dryRun)
+}
+
+func TestRepl_OutputDocumentChanges(t *testing.T) {
+	changes := []DocumentChange{
+		{
+			Path:   "users/abc",
+			Before: map[string]any{"name": "a", "age": int64(20), "address": map[string]any{"city": "Tokyo"}},
+			After:  map[string]any{"name": "a", "age": int64(21), "address": map[string]any{"city": "Osaka"}},
+		},
+		{Path: "users/def", Before: map[string]any{"name": "d"}, After: map[string]any{"name": "d"}},
+		{Path: "users/(auto ID)", After: map[string]any{"name": "n"}},
+		{Path: "users/old", Before: map[string]any{"name": "o"}},
+	}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputDocumentChanges(changes)
+	assert.Equal(t, `users/abc
+- address.city: "Tokyo"
++ address.city: "Osaka"
+- age: 20
++ age: 21
+
+users/def
+  (no changes)
+
+users/(auto ID) (new)
++ name: "n"
+
+users/old (deleted)
+- name: "o"
+
+4 documents would change
+(dry run, nothing was written)
+`, stdout.String())
+
+	stdout.Reset()
+	repl.outputMode = OutputModeJSON
+	repl.outputDocumentChanges(changes[2:])
+	assert.JSONEq(t, `[{"path":"users/(auto ID)","before":null,"after":{"name":"n"}},{"path":"users/old","before":{"name":"o"},"after":null}]`, stdout.String())
+}
+
+func TestRepl_Transaction(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+
+	repl.ProcessLine(`COMMIT`)
+	assert.Equal(t, "error: no transaction is open\n", stdout.String())
+
+	stdout.Reset()
+	repl.ProcessLine(`BEGIN`)
+	assert.NotNil(t, repl.tx)
+	prefix, _ := repl.livePrefix()
+	assert.Equal(t, "transaction> ", prefix)
+
+	repl.ProcessLine(`BEGIN`)
+	repl.ProcessLine(`COUNT users`)
+	assert.Equal(t, "error: a transaction is already open\nerror: COUNT is not supported in a transaction\n", stdout.String())
+
+	stdout.Reset()
+	repl.ProcessLine(`ROLLBACK`)
+	assert.Equal(t, "Rolled back\n", stdout.String())
+	assert.Nil(t, repl.tx)
+
+	stdout.Reset()
+	repl.ProcessLine(`BEGIN`)
+	repl.ProcessLine(`COMMIT`)
+	assert.Equal(t, "Committed\n", stdout.String())
+	_, ok := repl.livePrefix()
+	assert.False(t, ok)
+}
+
+func TestRepl_ConfirmWrite(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		want  string
+	}{
+		{
+			desc:  "insert with auto id",
+			input: `INSERT INTO users VALUES {name: "a"}`,
+			want:  "INSERT a new document into users\nContinue? [y/N] Canceled\n",
+		},
+		{
+			desc:  "set merge",
+			input: `SET users/abc {name: "a"} MERGE`,
+			want:  "SET users/abc (merge)\nContinue? [y/N] Canceled\n",
+		},
+		{
+			desc:  "update if updated at",
+			input: `UPDATE users/abc SET name = "a" IF UPDATED_AT = TIMESTAMP("2024-01-02T10:03:00.5Z")`,
+			want:  "UPDATE users/abc IF UPDATED_AT = TIMESTAMP(\"2024-01-02T10:03:00.5Z\")\nContinue? [y/N] Canceled\n",
+		},
+		{
+			desc:  "delete if exists",
+			input: `DELETE users/abc IF EXISTS`,
+			want:  "DELETE users/abc IF EXISTS\nContinue? [y/N] Canceled\n",
+		},
+		{
+			desc:  "copy recursive",
+			input: `COPY users/abc TO archive/2024/users/abc RECURSIVE`,
+			want:  "COPY users/abc to archive/2024/users/abc with its subcollections\nContinue? [y/N] Canceled\n",
+		},
+		{
+			desc:  "recursive delete",
+			input: `DELETE users/abc RECURSIVE`,
+			want:  "DELETE users/abc and its subcollections\nContinue? [y/N] Canceled\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var stdout bytes.Buffer
+			repl := NewRepl(context.Background(), nil, strings.NewReader("n\n"), &stdout, OutputModeTable)
+			repl.confirmWrites = true
+			repl.ProcessLine(tt.input)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+
+	// no answer cancels too
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, strings.NewReader(""), &stdout, OutputModeTable)
+	repl.confirmWrites = true
+	repl.ProcessLine(`UPDATE users/abc SET name = "a"`)
+	assert.Equal(t, "UPDATE users/abc\nContinue? [y/N] Canceled\n", stdout.String())
+}
+
+func TestRepl_OutputAggregate(t *testing.T) {
+	aggregates := []Aggregate{
+		NewAggregate(AGGREGATE_COUNT, "", "COUNT(*)"),
+		NewAggregate(AGGREGATE_SUM, "amount", "total"),
+		NewAggregate(AGGREGATE_AVG, "score", "AVG(score)"),
+	}
+	results := map[string]any{"COUNT(*)": int64(3), "total": int64(120), "AVG(score)": nil}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputAggregateTable(aggregates, results)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"COUNT(*)", "total", "AVG(score)"}, strings.Fields(strings.ReplaceAll(lines[1], "│", "")))
+	assert.Equal(t, []string{"3", "120", "(null)"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+
+	stdout.Reset()
+	repl.outputAggregateJSON(results)
+	assert.JSONEq(t, `{"COUNT(*)":3,"total":120,"AVG(score)":null}`, stdout.String())
+}
+
+func TestRepl_OutputRowsTable(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	rows := []map[string]any{
+		{"status": "paid", "COUNT(*)": int64(2)},
+		{"status": nil, "COUNT(*)": int64(1)},
+	}
+	repl.outputRowsTable([]string{"status", "COUNT(*)"}, rows)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"status", "COUNT(*)"}, strings.Fields(strings.ReplaceAll(lines[1], "│", "")))
+	assert.Equal(t, []string{"paid", "2"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+	assert.Equal(t, []string{"(null)", "1"}, strings.Fields(strings.ReplaceAll(lines[4], "│", "")))
+}
+
+func TestRepl_OutputExplain(t *testing.T) {
+	duration := 12 * time.Millisecond
+	metrics := &firestore.ExplainMetrics{
+		PlanSummary: &firestore.PlanSummary{
+			IndexesUsed: []*map[string]any{
+				{"query_scope": "Collection", "properties": "(age ASC, __name__ ASC)"},
+			},
+		},
+		ExecutionStats: &firestore.ExecutionStats{
+			ResultsReturned:   2,
+			ReadOperations:    2,
+			ExecutionDuration: &duration,
+			DebugStats: &map[string]any{
+				"documents_scanned":     "2",
+				"index_entries_scanned": "3",
+			},
+		},
+	}
+
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+	repl.outputExplainTable(metrics)
+
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"Query", "Scope", "Properties"}, strings.Fields(strings.ReplaceAll(lines[1], "│", "")))
+	assert.Equal(t, []string{"Collection", "(age", "ASC,", "__name__", "ASC)"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+	assert.Contains(t, stdout.String(), "Index entries scanned")
+	assert.Contains(t, stdout.String(), "12ms")
+
+	stdout.Reset()
+	repl.outputExplainJSON(metrics)
+	assert.JSONEq(t, `{
+		"indexesUsed": [{"query_scope": "Collection", "properties": "(age ASC, __name__ ASC)"}],
+		"executionStats": {
+			"resultsReturned": 2,
+			"documentsScanned": "2",
+			"indexEntriesScanned": "3",
+			"readOperations": 2,
+			"executionDuration": "12ms",
+			"debugStats": {"documents_scanned": "2", "index_entries_scanned": "3"}
+		}
+	}`, stdout.String())
+
+	stdout.Reset()
+	repl.outputExplainJSON(&firestore.ExplainMetrics{PlanSummary: metrics.PlanSummary})
+	assert.JSONEq(t, `{"indexesUsed": [{"query_scope": "Collection", "properties": "(age ASC, __name__ ASC)"}]}`, stdout.String())
+}
+
+func TestRepl_AsOf(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeJSON)
+
+	repl.ProcessLine(`\asof TIMESTAMP("2024-01-02T10:03:00Z")`)
+	assert.Equal(t, time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC), repl.asOf)
+	prefix, ok := repl.livePrefix()
+	assert.True(t, ok)
+	assert.Equal(t, "as of 2024-01-02T10:03:00Z> ", prefix)
+
+	repl.outputDocJSON("1", map[string]any{"name": "user-1"}, time.Time{}, repl.asOf)
+	assert.JSONEq(t, `{"id":"1","data":{"name":"user-1"},"readTime":"2024-01-02T10:03:00Z"}`, stdout.String())
+
+	repl.ProcessLine(`\asof off`)
+	assert.True(t, repl.asOf.IsZero())
+	_, ok = repl.livePrefix()
+	assert.False(t, ok)
+}
+
+func TestRepl_Timezone(t *testing.T) {
+	var stdout bytes.Buffer
+	repl := NewRepl(context.Background(), nil, nil, &stdout, OutputModeTable)
+
+	repl.ProcessLine(`\timezone`)
+	assert.Equal(t, "UTC\n", stdout.String())
+
+	repl.ProcessLine(`\timezone Asia/Tokyo`)
+	assert.Equal(t, "Asia/Tokyo", repl.location.String())
+
+	repl.ProcessLine(`\asof TIMESTAMP("2024-01-02 19:03")`)
+	assert.True(t, time.Date(2024, 1, 2, 10, 3, 0, 0, time.UTC).Equal(repl.asOf))
+	prefix, _ := repl.livePrefix()
+	assert.Equal(t, "as of 2024-01-02T19:03:00+09:00> ", prefix)
+
+	stdout.Reset()
+	repl.outputDocTable("1", map[string]any{"createdAt": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, nil)
+	lines := strings.Split(stdout.String(), "\n")
+	assert.Equal(t, []string{"1", "2024-01-02T09:00:00+09:00"}, strings.Fields(strings.ReplaceAll(lines[3], "│", "")))
+}
@@ -0,0 +1,109 @@
+package fscli
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the time zone database so that zone names such as Asia/Tokyo
+	// work on systems without one.
+	_ "time/tzdata"
+)
+
+// timeLayouts are the accepted TIMESTAMP formats. Layouts without a zone
+// are read in the session time zone unless one is given.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseTime(timeStr string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, timeStr, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp format: %s", timeStr)
+}
+
+var offsetPattern = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+
+// loadLocation resolves a time zone given as UTC, Local, an offset such as
+// +09:00, or an IANA name such as Asia/Tokyo.
+func loadLocation(name string) (*time.Location, error) {
+	if strings.EqualFold(name, "UTC") || name == "Z" {
+		return time.UTC, nil
+	}
+	if strings.EqualFold(name, "Local") {
+		return time.Local, nil
+	}
+	if m := offsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 23 || minutes > 59 {
+			return nil, fmt.Errorf("invalid time zone: %s", name)
+		}
+		offset := hours*60*60 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return nil, fmt.Errorf("invalid time zone: %s", name)
+	}
+	return loc, nil
+}
+
+// startOfDay returns midnight of the day of t in loc.
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// addInterval adds an interval such as "7 days" or "1 hour 30 minutes"
+// to t, or subtracts it when sign is negative. Days and longer units
+// follow the calendar of the location of t.
+func addInterval(t time.Time, interval string, sign int) (time.Time, error) {
+	fields := strings.Fields(interval)
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return time.Time{}, fmt.Errorf("invalid interval: %s", interval)
+	}
+
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid interval: %s", interval)
+		}
+		n *= sign
+
+		switch strings.TrimSuffix(strings.ToLower(fields[i+1]), "s") {
+		case "second":
+			t = t.Add(time.Duration(n) * time.Second)
+		case "minute":
+			t = t.Add(time.Duration(n) * time.Minute)
+		case "hour":
+			t = t.Add(time.Duration(n) * time.Hour)
+		case "day":
+			t = t.AddDate(0, 0, n)
+		case "week":
+			t = t.AddDate(0, 0, n*7)
+		case "month":
+			t = t.AddDate(0, n, 0)
+		case "year":
+			t = t.AddDate(n, 0, 0)
+		default:
+			return time.Time{}, fmt.Errorf("invalid interval unit: %s", fields[i+1])
+		}
+	}
+	return t, nil
+}
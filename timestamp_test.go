@@ -0,0 +1,110 @@
+package fscli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc  string
+		input string
+		loc   *time.Location
+		want  time.Time
+	}{
+		{"date", "2024-01-02", time.UTC, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"date in location", "2024-01-02", tokyo, time.Date(2024, 1, 2, 0, 0, 0, 0, tokyo)},
+		{"date and time with space", "2024-01-02 09:30", tokyo, time.Date(2024, 1, 2, 9, 30, 0, 0, tokyo)},
+		{"date and time with seconds", "2024-01-02T09:30:15", time.UTC, time.Date(2024, 1, 2, 9, 30, 15, 0, time.UTC)},
+		{"offset wins over location", "2024-01-02T09:00:00Z", tokyo, time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"fractional seconds", "2024-01-02T09:00:00.5Z", time.UTC, time.Date(2024, 1, 2, 9, 0, 0, 500000000, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := parseTime(tt.input, tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+			_, wantOffset := tt.want.Zone()
+			_, gotOffset := got.Zone()
+			assert.Equal(t, wantOffset, gotOffset)
+		})
+	}
+
+	_, err = parseTime("01/02/2024", time.UTC)
+	assert.Error(t, err)
+}
+
+func TestLoadLocation(t *testing.T) {
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		desc       string
+		input      string
+		wantOffset int
+	}{
+		{"utc", "UTC", 0},
+		{"zone name", "Asia/Tokyo", 9 * 60 * 60},
+		{"offset", "+09:00", 9 * 60 * 60},
+		{"negative offset without colon", "-0530", -(5*60*60 + 30*60)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			loc, err := loadLocation(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, offset := at.In(loc).Zone()
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+
+	for _, input := range []string{"", "Mars/Olympus", "+25:00"} {
+		_, err := loadLocation(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestAddInterval(t *testing.T) {
+	base := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		desc     string
+		interval string
+		sign     int
+		want     time.Time
+	}{
+		{"days", "7 days", -1, time.Date(2024, 1, 24, 12, 0, 0, 0, time.UTC)},
+		{"singular unit", "1 hour", 1, time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC)},
+		{"several units", "1 day 30 minutes", 1, time.Date(2024, 2, 1, 12, 30, 0, 0, time.UTC)},
+		{"weeks", "2 weeks", 1, time.Date(2024, 2, 14, 12, 0, 0, 0, time.UTC)},
+		{"months", "1 month", -1, time.Date(2023, 12, 31, 12, 0, 0, 0, time.UTC)},
+		{"years with upper case", "1 YEAR", 1, time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{"seconds", "90 seconds", 1, time.Date(2024, 1, 31, 12, 1, 30, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := addInterval(base, tt.interval, tt.sign)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, interval := range []string{"", "7", "days 7", "1 fortnight"} {
+		_, err := addInterval(base, interval, 1)
+		assert.Error(t, err, interval)
+	}
+}
@@ -9,6 +9,26 @@ const (
 	GET              = "GET"
 	QUERY            = "QUERY"
 	COUNT            = "COUNT"
+	AGGREGATE        = "AGGREGATE"
+	EXPLAIN          = "EXPLAIN"
+	ANALYZE          = "ANALYZE"
+	INSERT           = "INSERT"
+	INTO             = "INTO"
+	VALUES           = "VALUES"
+	SET              = "SET"
+	MERGE            = "MERGE"
+	UPDATE           = "UPDATE"
+	DELETE           = "DELETE"
+	FROM             = "FROM"
+	RECURSIVE        = "RECURSIVE"
+	DROP             = "DROP"
+	COLLECTION       = "COLLECTION"
+	COPY             = "COPY"
+	BEGIN            = "BEGIN"
+	COMMIT           = "COMMIT"
+	ROLLBACK         = "ROLLBACK"
+	IF               = "IF"
+	EXISTS           = "EXISTS"
 	SELECT           = "SELECT"
 	COLLECTION_GROUP = "COLLECTION_GROUP"
 
@@ -20,8 +40,11 @@ const (
 	LT                 = "<"
 	LTE                = "<="
 	IN                 = "IN"
+	NOT_IN             = "NOT_IN"
 	ARRAY_CONTAINS     = "ARRAY_CONTAINS"
 	ARRAY_CONTAINS_ANY = "ARRAY_CONTAINS_ANY"
+	IS                 = "IS"
+	GROUP              = "GROUP"
 	ORDER              = "ORDER"
 	BY                 = "BY"
 
@@ -32,21 +55,84 @@ const (
 	STRING = "STRING"
 	INT    = "INT"
 	FLOAT  = "FLOAT"
+	TRUE   = "TRUE"
+	FALSE  = "FALSE"
+	NULL   = "NULL"
+	NAN    = "NAN"
 
 	AND = "AND"
+	OR  = "OR"
+	NOT = "NOT"
 
-	LIMIT = "LIMIT"
+	LIMIT  = "LIMIT"
+	LAST   = "LAST"
+	OFFSET = "OFFSET"
+
+	START  = "START"
+	END    = "END"
+	AT     = "AT"
+	AFTER  = "AFTER"
+	BEFORE = "BEFORE"
 
 	LBRACKET = "["
 	RBRACKET = "]"
 	LPAREN   = "("
 	RPAREN   = ")"
 	COMMA    = ","
+	ASTERISK = "*"
+	PLUS     = "+"
+	MINUS    = "-"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	COLON    = ":"
+
+	AS = "AS"
+	OF = "OF"
+
+	INTERVAL = "INTERVAL"
+
+	// ID is matched by literal after INSERT INTO only, since id is
+	// a common field name.
+	ID = "ID"
+
+	// UPDATED_AT is matched by literal after IF only, since updated_at is
+	// a common field name.
+	UPDATED_AT = "UPDATED_AT"
+
+	// The FIND NEAREST words are matched by literal inside FIND NEAREST
+	// only, since to, distance and field are common field names.
+	FIND        = "FIND"
+	NEAREST     = "NEAREST"
+	TO          = "TO"
+	DISTANCE    = "DISTANCE"
+	FIELD       = "FIELD"
+	COSINE      = "COSINE"
+	EUCLIDEAN   = "EUCLIDEAN"
+	DOT_PRODUCT = "DOT_PRODUCT"
 
 	F_TIMESTAMP = "TIMESTAMP"
+	F_NOW       = "NOW"
+	F_TODAY     = "TODAY"
+	F_SUM       = "SUM"
+	F_AVG       = "AVG"
+	F_MIN       = "MIN"
+	F_MAX       = "MAX"
+	F_VECTOR    = "VECTOR"
+	F_REF       = "REF"
+	F_GEOPOINT  = "GEOPOINT"
+	F_BYTES     = "BYTES"
+
+	F_INCREMENT        = "INCREMENT"
+	F_ARRAY_UNION      = "ARRAY_UNION"
+	F_ARRAY_REMOVE     = "ARRAY_REMOVE"
+	F_DELETE_FIELD     = "DELETE_FIELD"
+	F_SERVER_TIMESTAMP = "SERVER_TIMESTAMP"
 
 	LIST_COLLECTIONS = "LIST_COLLECTIONS"
 	PAGER            = "PAGER"
+	SET_AS_OF        = "SET_AS_OF"
+	SET_TIMEZONE     = "SET_TIMEZONE"
+	SET_DRY_RUN      = "SET_DRY_RUN"
 )
 
 type TokenType = string
@@ -60,15 +146,53 @@ var keywords = map[string]TokenType{
 	"GET":              GET,
 	"QUERY":            QUERY,
 	"COUNT":            COUNT,
+	"AGGREGATE":        AGGREGATE,
+	"EXPLAIN":          EXPLAIN,
+	"ANALYZE":          ANALYZE,
+	"INSERT":           INSERT,
+	"INTO":             INTO,
+	"VALUES":           VALUES,
+	"SET":              SET,
+	"MERGE":            MERGE,
+	"UPDATE":           UPDATE,
+	"DELETE":           DELETE,
+	"FROM":             FROM,
+	"RECURSIVE":        RECURSIVE,
+	"DROP":             DROP,
+	"COLLECTION":       COLLECTION,
+	"COPY":             COPY,
+	"BEGIN":            BEGIN,
+	"COMMIT":           COMMIT,
+	"ROLLBACK":         ROLLBACK,
+	"IF":               IF,
+	"EXISTS":           EXISTS,
 	"SELECT":           SELECT,
 	"COLLECTION_GROUP": COLLECTION_GROUP,
 	"WHERE":            WHERE,
 	"AND":              AND,
+	"OR":               OR,
+	"NOT":              NOT,
+	"IS":               IS,
+	"TRUE":             TRUE,
+	"FALSE":            FALSE,
+	"NULL":             NULL,
+	"NAN":              NAN,
+	"GROUP":            GROUP,
 	"ORDER":            ORDER,
 	"BY":               BY,
 	"ASC":              ASC,
 	"DESC":             DESC,
 	"LIMIT":            LIMIT,
+	"LAST":             LAST,
+	"OFFSET":           OFFSET,
+	"START":            START,
+	"END":              END,
+	"AT":               AT,
+	"AFTER":            AFTER,
+	"BEFORE":           BEFORE,
+	"AS":               AS,
+	"OF":               OF,
+	"INTERVAL":         INTERVAL,
 }
 
 var operators = map[string]TokenType{
@@ -79,13 +203,17 @@ var operators = map[string]TokenType{
 	"<":                  LT,
 	"<=":                 LTE,
 	"IN":                 IN,
+	"NOT_IN":             NOT_IN,
 	"ARRAY_CONTAINS":     ARRAY_CONTAINS,
 	"ARRAY_CONTAINS_ANY": ARRAY_CONTAINS_ANY,
 }
 
 var metacommands = map[string]TokenType{
-	`\d`:     LIST_COLLECTIONS,
-	`\pager`: PAGER,
+	`\d`:        LIST_COLLECTIONS,
+	`\pager`:    PAGER,
+	`\asof`:     SET_AS_OF,
+	`\timezone`: SET_TIMEZONE,
+	`\dryrun`:   SET_DRY_RUN,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -99,6 +227,12 @@ func LookupIdent(ident string) TokenType {
 	return IDENT
 }
 
+// isName reports whether tok can name a field or collection. Keywords are
+// accepted as well, so fields such as start or order need no backticks.
+func isName(tok Token) bool {
+	return tok.Type == IDENT || keywords[strings.ToUpper(tok.Literal)] == tok.Type
+}
+
 func LookupMetacommand(s string) TokenType {
 	if tok, ok := metacommands[s]; ok {
 		return tok
@@ -0,0 +1,381 @@
+package fscli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrTransactionDataChanged is returned when a retried transaction
+	// reads different documents than the ones shown before COMMIT.
+	ErrTransactionDataChanged = errors.New("data read in the transaction changed")
+	// ErrTransactionExpired is returned when Firestore has expired a
+	// transaction that was open or idle for too long.
+	ErrTransactionExpired = errors.New("transaction expired; run the block again")
+
+	errReadAfterWrite = errors.New("reads must come before writes in a transaction")
+	errRollback       = errors.New("rollback")
+)
+
+// Firestore expires a transaction after transactionIdleLimit without
+// a request, or transactionLimit after it started.
+const (
+	transactionIdleLimit = 60 * time.Second
+	transactionLimit     = 270 * time.Second
+)
+
+// Transaction is a BEGIN block. Reads run in a Firestore transaction as
+// they are entered and writes are queued until Commit. A block without
+// reads is committed as a write batch.
+type Transaction struct {
+	exe      *Executor
+	reads    []transactionRead
+	writes   []transactionWrite
+	attempts int
+	onRetry  func(attempt int)
+
+	// Set when the first read starts the Firestore transaction.
+	tx       *firestore.Transaction
+	started  time.Time
+	lastUsed time.Time
+	ready    chan *firestore.Transaction
+	finish   chan bool
+	done     chan error
+	commit   firestore.CommitResponse
+}
+
+// transactionRead is a read that runs again when the transaction is
+// retried, with the versions of the documents it returned.
+type transactionRead struct {
+	run      func(ctx context.Context, exe *Executor) ([]*firestore.DocumentSnapshot, error)
+	versions []string
+}
+
+// transactionWrite is a queued write of op to ref.
+type transactionWrite struct {
+	op    Operation
+	ref   *firestore.DocumentRef
+	write func(w writeQueue) error
+}
+
+// writeQueue queues writes in a transaction or a write batch.
+type writeQueue interface {
+	Create(ref *firestore.DocumentRef, data any) error
+	Set(ref *firestore.DocumentRef, data any, opts ...firestore.SetOption) error
+	Update(ref *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) error
+	Delete(ref *firestore.DocumentRef, preconds ...firestore.Precondition) error
+}
+
+// batchQueue queues writes in a write batch, which reports errors on
+// Commit.
+type batchQueue struct {
+	batch *firestore.WriteBatch
+}
+
+func (q batchQueue) Create(ref *firestore.DocumentRef, data any) error {
+	q.batch.Create(ref, data)
+	return nil
+}
+
+func (q batchQueue) Set(ref *firestore.DocumentRef, data any, opts ...firestore.SetOption) error {
+	q.batch.Set(ref, data, opts...)
+	return nil
+}
+
+func (q batchQueue) Update(ref *firestore.DocumentRef, updates []firestore.Update, preconds ...firestore.Precondition) error {
+	q.batch.Update(ref, updates, preconds...)
+	return nil
+}
+
+func (q batchQueue) Delete(ref *firestore.DocumentRef, preconds ...firestore.Precondition) error {
+	q.batch.Delete(ref, preconds...)
+	return nil
+}
+
+// NewTransaction starts a BEGIN block. onRetry is called before the
+// transaction is run again after a concurrent write aborted it.
+func NewTransaction(exe *Executor, onRetry func(attempt int)) *Transaction {
+	return &Transaction{exe: exe, onRetry: onRetry}
+}
+
+// ExecuteGet reads a document in the transaction.
+func (t *Transaction) ExecuteGet(ctx context.Context, op *GetOperation) (*firestore.DocumentSnapshot, error) {
+	if !op.asOf.IsZero() {
+		return nil, fmt.Errorf("AS OF is %w", ErrNotInTransaction)
+	}
+	docs, err := t.read(ctx, func(ctx context.Context, exe *Executor) ([]*firestore.DocumentSnapshot, error) {
+		doc, err := exe.ExecuteGet(ctx, op)
+		if err != nil {
+			return nil, err
+		}
+		return []*firestore.DocumentSnapshot{doc}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+// ExecuteQuery runs a query in the transaction.
+func (t *Transaction) ExecuteQuery(ctx context.Context, op *QueryOperation) ([]*firestore.DocumentSnapshot, error) {
+	if !op.asOf.IsZero() {
+		return nil, fmt.Errorf("AS OF is %w", ErrNotInTransaction)
+	}
+	return t.read(ctx, func(ctx context.Context, exe *Executor) ([]*firestore.DocumentSnapshot, error) {
+		return exe.ExecuteQuery(ctx, op)
+	})
+}
+
+func (t *Transaction) read(ctx context.Context, run func(ctx context.Context, exe *Executor) ([]*firestore.DocumentSnapshot, error)) ([]*firestore.DocumentSnapshot, error) {
+	if len(t.writes) > 0 {
+		return nil, errReadAfterWrite
+	}
+	if t.tx == nil {
+		if err := t.start(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	docs, err := run(ctx, t.exe.withTransaction(t.tx))
+	if err != nil {
+		return nil, t.expiredError(err, time.Now())
+	}
+	t.lastUsed = time.Now()
+	t.reads = append(t.reads, transactionRead{run: run, versions: documentVersions(docs)})
+	return docs, nil
+}
+
+// start runs RunTransaction in the background. Its function hands the
+// transaction over and waits for Commit or Rollback.
+func (t *Transaction) start(ctx context.Context) error {
+	t.ready = make(chan *firestore.Transaction)
+	t.finish = make(chan bool)
+	t.done = make(chan error, 1)
+	go func() {
+		t.done <- t.exe.fs.RunTransaction(ctx, t.run, firestore.WithCommitResponseTo(&t.commit))
+	}()
+
+	select {
+	case t.tx = <-t.ready:
+		t.started = time.Now()
+		t.lastUsed = t.started
+		return nil
+	case err := <-t.done:
+		return err
+	}
+}
+
+// run is the function of RunTransaction. A retry reads again what was
+// read before and fails if any of it changed, because the queued writes
+// may depend on it.
+func (t *Transaction) run(ctx context.Context, tx *firestore.Transaction) error {
+	t.attempts++
+	if t.attempts == 1 {
+		t.ready <- tx
+		if commit := <-t.finish; !commit {
+			return errRollback
+		}
+	} else {
+		if t.onRetry != nil {
+			t.onRetry(t.attempts)
+		}
+		exe := t.exe.withTransaction(tx)
+		for _, read := range t.reads {
+			docs, err := read.run(ctx, exe)
+			if err != nil {
+				return err
+			}
+			if !slices.Equal(read.versions, documentVersions(docs)) {
+				return ErrTransactionDataChanged
+			}
+		}
+	}
+
+	for _, w := range t.writes {
+		if err := w.write(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Queue adds the write of op to the transaction.
+func (t *Transaction) Queue(op Operation) error {
+	w, err := t.exe.transactionWrite(op)
+	if err != nil {
+		return err
+	}
+	t.writes = append(t.writes, w)
+	return nil
+}
+
+// Writes returns the queued writes.
+func (t *Transaction) Writes() []Operation {
+	ops := make([]Operation, 0, len(t.writes))
+	for _, w := range t.writes {
+		ops = append(ops, w.op)
+	}
+	return ops
+}
+
+// Attempts returns how many times the Firestore transaction was run.
+func (t *Transaction) Attempts() int {
+	return t.attempts
+}
+
+// Commit commits the queued writes. The transaction is retried when a
+// concurrent write aborts it.
+func (t *Transaction) Commit(ctx context.Context) ([]*WriteResult, error) {
+	if t.tx == nil {
+		return t.commitBatch(ctx)
+	}
+
+	t.finish <- true
+	if err := <-t.done; err != nil {
+		return nil, t.expiredError(err, time.Now())
+	}
+	results := make([]*WriteResult, 0, len(t.writes))
+	for _, w := range t.writes {
+		results = append(results, &WriteResult{Path: refPath(w.ref), UpdateTime: t.commit.CommitTime()})
+	}
+	return results, nil
+}
+
+// commitBatch commits the writes of a block without reads. WriteBatch is
+// deprecated, but it commits atomically without starting a transaction.
+func (t *Transaction) commitBatch(ctx context.Context) ([]*WriteResult, error) {
+	results := make([]*WriteResult, 0, len(t.writes))
+	if len(t.writes) == 0 {
+		return results, nil
+	}
+
+	batch := t.exe.fs.Batch()
+	for _, w := range t.writes {
+		if err := w.write(batchQueue{batch}); err != nil {
+			return nil, err
+		}
+	}
+	wrs, err := batch.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, w := range t.writes {
+		results = append(results, newWriteResult(w.ref, wrs[i]))
+	}
+	return results, nil
+}
+
+// Rollback discards the queued writes.
+func (t *Transaction) Rollback() error {
+	if t.tx == nil {
+		return nil
+	}
+	t.finish <- false
+	err := <-t.done
+	// an expired transaction has nothing left to roll back
+	if err == errRollback || errors.Is(t.expiredError(err, time.Now()), ErrTransactionExpired) {
+		return nil
+	}
+	return err
+}
+
+// expiredError replaces err with ErrTransactionExpired when Firestore
+// rejected the transaction because it expired. Firestore reports this as
+// an invalid or aborted transaction, so the time the transaction has been
+// open or idle tells it apart.
+func (t *Transaction) expiredError(err error, now time.Time) error {
+	code := status.Code(err)
+	if code != codes.InvalidArgument && code != codes.Aborted {
+		return err
+	}
+	if strings.Contains(status.Convert(err).Message(), "expired") ||
+		now.Sub(t.lastUsed) >= transactionIdleLimit ||
+		now.Sub(t.started) >= transactionLimit {
+		return fmt.Errorf("%w: %s", ErrTransactionExpired, status.Convert(err).Message())
+	}
+	return err
+}
+
+// transactionWrite resolves op into a write for a transaction. Only
+// writes of a single document can be queued.
+func (exe *Executor) transactionWrite(op Operation) (transactionWrite, error) {
+	collection := exe.fs.Collection(op.Collection())
+	if collection == nil {
+		return transactionWrite{}, ErrInvalidCollection
+	}
+
+	switch v := op.(type) {
+	case *InsertOperation:
+		var ref *firestore.DocumentRef
+		if v.docId == "" {
+			ref = collection.NewDoc()
+		} else {
+			ref = collection.Doc(v.docId)
+		}
+		data := exe.resolveValue(v.data)
+		return transactionWrite{op: op, ref: ref, write: func(w writeQueue) error {
+			return w.Create(ref, data)
+		}}, nil
+	case *SetOperation:
+		ref := collection.Doc(v.docId)
+		data := exe.resolveValue(v.data)
+		if v.precondition != nil {
+			// Only IF NOT EXISTS maps to a write; the others need a read.
+			if v.precondition.preconditionType != PRECONDITION_NOT_EXISTS {
+				return transactionWrite{}, fmt.Errorf("SET IF %s is %w", v.precondition.preconditionType, ErrNotInTransaction)
+			}
+			return transactionWrite{op: op, ref: ref, write: func(w writeQueue) error {
+				return w.Create(ref, data)
+			}}, nil
+		}
+		var opts []firestore.SetOption
+		if v.merge {
+			opts = append(opts, firestore.MergeAll)
+		}
+		return transactionWrite{op: op, ref: ref, write: func(w writeQueue) error {
+			return w.Set(ref, data, opts...)
+		}}, nil
+	case *UpdateOperation:
+		if v.IsBulk() {
+			return transactionWrite{}, fmt.Errorf("UPDATE of a collection is %w", ErrNotInTransaction)
+		}
+		updates, err := exe.toUpdates(v.updates)
+		if err != nil {
+			return transactionWrite{}, err
+		}
+		ref := collection.Doc(v.docId)
+		return transactionWrite{op: op, ref: ref, write: func(w writeQueue) error {
+			return w.Update(ref, updates, toPreconditions(v.precondition, true)...)
+		}}, nil
+	case *DeleteOperation:
+		if v.IsBulk() {
+			return transactionWrite{}, fmt.Errorf("DELETE FROM is %w", ErrNotInTransaction)
+		}
+		if v.recursive {
+			return transactionWrite{}, fmt.Errorf("RECURSIVE is %w", ErrNotInTransaction)
+		}
+		ref := collection.Doc(v.docId)
+		return transactionWrite{op: op, ref: ref, write: func(w writeQueue) error {
+			return w.Delete(ref, toPreconditions(v.precondition, false)...)
+		}}, nil
+	default:
+		return transactionWrite{}, fmt.Errorf("%s is %w", op.OperationType(), ErrNotInTransaction)
+	}
+}
+
+// documentVersions identifies docs by path and update time.
+func documentVersions(docs []*firestore.DocumentSnapshot) []string {
+	versions := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		versions = append(versions, refPath(doc.Ref)+"@"+doc.UpdateTime.Format(time.RFC3339Nano))
+	}
+	return versions
+}
@@ -0,0 +1,130 @@
+package fscli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTransaction(t *testing.T) {
+	os.Setenv("FIRESTORE_EMULATOR_HOST", "127.0.0.1:8080")
+	ctx := context.Background()
+	fs, err := firestore.NewClient(ctx, "fscli-executor-test-transaction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := NewExecutor(ctx, fs)
+	defer func() {
+		for _, id := range []string{"a", "b"} {
+			fs.Collection("accounts").Doc(id).Delete(ctx)
+		}
+	}()
+
+	// Without reads the writes are committed as a batch.
+	tx := NewTransaction(exe, nil)
+	assert.NoError(t, tx.Queue(NewInsertOperation("accounts", "a", map[string]any{"balance": int64(100)})))
+	assert.NoError(t, tx.Queue(NewSetOperation("accounts", "b", map[string]any{"balance": int64(0)}, false)))
+	results, err := tx.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"accounts/a", "accounts/b"}, []string{results[0].Path, results[1].Path})
+
+	// Reads run in the transaction and must come before writes.
+	tx = NewTransaction(exe, nil)
+	doc, err := tx.ExecuteGet(ctx, &GetOperation{collection: "accounts", docId: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(100), doc.Data()["balance"])
+	assert.NoError(t, tx.Queue(NewUpdateOperation("accounts", "a", []Update{NewUpdate("balance", NewTransform(TRANSFORM_INCREMENT, []any{int64(-30)}))})))
+	assert.NoError(t, tx.Queue(NewUpdateOperation("accounts", "b", []Update{NewUpdate("balance", NewTransform(TRANSFORM_INCREMENT, []any{int64(30)}))})))
+	_, err = tx.ExecuteQuery(ctx, &QueryOperation{collection: "accounts"})
+	assert.Error(t, err)
+	_, err = tx.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, tx.Attempts())
+
+	// A rollback writes nothing.
+	tx = NewTransaction(exe, nil)
+	docs, err := tx.ExecuteQuery(ctx, &QueryOperation{collection: "accounts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, docs, 2)
+	assert.NoError(t, tx.Queue(NewDeleteOperation("accounts", "a", false)))
+	assert.NoError(t, tx.Rollback())
+
+	snap, err := fs.Collection("accounts").Doc("a").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(70), snap.Data()["balance"])
+
+	tx = NewTransaction(exe, nil)
+	assert.ErrorIs(t, tx.Queue(NewDeleteOperation("accounts", "a", true)), ErrNotInTransaction)
+}
+
+func TestTransactionExpiredError(t *testing.T) {
+	started := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		desc    string
+		err     error
+		now     time.Time
+		expired bool
+	}{
+		{
+			desc:    "expired message",
+			err:     status.Error(codes.InvalidArgument, "The referenced transaction has expired or is no longer valid."),
+			now:     started.Add(time.Second),
+			expired: true,
+		},
+		{
+			desc:    "idle",
+			err:     status.Error(codes.InvalidArgument, "invalid transaction"),
+			now:     started.Add(transactionIdleLimit),
+			expired: true,
+		},
+		{
+			desc:    "aborted while idle",
+			err:     status.Error(codes.Aborted, "transaction aborted"),
+			now:     started.Add(2 * transactionIdleLimit),
+			expired: true,
+		},
+		{
+			desc: "invalid argument in time",
+			err:  status.Error(codes.InvalidArgument, "invalid field"),
+			now:  started.Add(time.Second),
+		},
+		{
+			desc: "other error after the limit",
+			err:  status.Error(codes.FailedPrecondition, "precondition failed"),
+			now:  started.Add(transactionLimit),
+		},
+		{
+			desc: "not a status",
+			err:  errors.New("failed"),
+			now:  started.Add(transactionLimit),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			tx := &Transaction{started: started, lastUsed: started}
+			err := tx.expiredError(tt.err, tt.now)
+			assert.Equal(t, tt.expired, errors.Is(err, ErrTransactionExpired))
+			if !tt.expired {
+				assert.Equal(t, tt.err, err)
+			}
+		})
+	}
+}